@@ -0,0 +1,208 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Class identifies one of the error classes defined by this package. Every
+// error resolves to exactly one class, see ClassOf.
+//
+// The zero value is ClassUnknown.
+type Class uint8
+
+// Error classes in the order the errors are defined by this package,
+// followed by the classes for the context errors.
+const (
+	ClassUnknown Class = iota
+	ClassInvalidArgument
+	ClassNotFound
+	ClassAlreadyExists
+	ClassPermissionDenied
+	ClassResourceExhausted
+	ClassFailedPrecondition
+	ClassConflict
+	ClassNotModified
+	ClassAborted
+	ClassOutOfRange
+	ClassNotImplemented
+	ClassInternal
+	ClassUnavailable
+	ClassDataLoss
+	ClassUnauthenticated
+	ClassDeadlineExceeded
+	ClassCanceled
+
+	numClasses = iota
+)
+
+// ClassInfo describes how an error class is represented by this package and
+// how it maps onto other error domains.
+type ClassInfo struct {
+	// Class is the error class being described.
+	Class Class
+
+	// Name is the canonical upper snake case name of the class, such as
+	// "NOT_FOUND". This is the value returned by Class.String.
+	Name string
+
+	// Err is the error which the class resolves to, either one of the
+	// errors defined by this package or a context error.
+	Err error
+
+	// Interface is the name of the single method interface which marks an
+	// error as belonging to the class. These match the interfaces used by
+	// Moby, such as "NotFound" or "InvalidParameter".
+	Interface string
+
+	// GRPCCode is the numeric gRPC status code the class is sent as.
+	GRPCCode uint32
+
+	// HTTPStatus is the HTTP status code the class is sent as.
+	HTTPStatus int
+}
+
+var classes = [numClasses]ClassInfo{
+	{ClassUnknown, "UNKNOWN", ErrUnknown, "Unknown", 2, 500},
+	{ClassInvalidArgument, "INVALID_ARGUMENT", ErrInvalidArgument, "InvalidParameter", 3, 400},
+	{ClassNotFound, "NOT_FOUND", ErrNotFound, "NotFound", 5, 404},
+	{ClassAlreadyExists, "ALREADY_EXISTS", ErrAlreadyExists, "AlreadyExists", 6, 409},
+	{ClassPermissionDenied, "PERMISSION_DENIED", ErrPermissionDenied, "Forbidden", 7, 403},
+	{ClassResourceExhausted, "RESOURCE_EXHAUSTED", ErrResourceExhausted, "ResourceExhausted", 8, 429},
+	{ClassFailedPrecondition, "FAILED_PRECONDITION", ErrFailedPrecondition, "FailedPrecondition", 9, 412},
+	{ClassConflict, "CONFLICT", ErrConflict, "Conflict", 9, 409},
+	{ClassNotModified, "NOT_MODIFIED", ErrNotModified, "NotModified", 9, 304},
+	{ClassAborted, "ABORTED", ErrAborted, "Aborted", 10, 409},
+	{ClassOutOfRange, "OUT_OF_RANGE", ErrOutOfRange, "OutOfRange", 11, 416},
+	{ClassNotImplemented, "NOT_IMPLEMENTED", ErrNotImplemented, "NotImplemented", 12, 501},
+	{ClassInternal, "INTERNAL", ErrInternal, "System", 13, 500},
+	{ClassUnavailable, "UNAVAILABLE", ErrUnavailable, "Unavailable", 14, 503},
+	{ClassDataLoss, "DATA_LOSS", ErrDataLoss, "DataLoss", 15, 500},
+	{ClassUnauthenticated, "UNAUTHENTICATED", ErrUnauthenticated, "Unauthorized", 16, 401},
+	{ClassDeadlineExceeded, "DEADLINE_EXCEEDED", context.DeadlineExceeded, "DeadlineExceeded", 4, 504},
+	{ClassCanceled, "CANCELED", context.Canceled, "Cancelled", 1, 499},
+}
+
+// Classes returns the description of every error class, ordered by class.
+// The returned slice may be modified by the caller.
+func Classes() []ClassInfo {
+	c := make([]ClassInfo, numClasses)
+	copy(c, classes[:])
+	return c
+}
+
+// ClassOf returns the class of the error as determined by Resolve. Errors
+// which do not resolve to any defined error, including nil, are reported as
+// ClassUnknown.
+func ClassOf(err error) Class {
+	if err == nil {
+		return ClassUnknown
+	}
+	switch Resolve(err) {
+	case ErrInvalidArgument:
+		return ClassInvalidArgument
+	case ErrNotFound:
+		return ClassNotFound
+	case ErrAlreadyExists:
+		return ClassAlreadyExists
+	case ErrPermissionDenied:
+		return ClassPermissionDenied
+	case ErrResourceExhausted:
+		return ClassResourceExhausted
+	case ErrFailedPrecondition:
+		return ClassFailedPrecondition
+	case ErrConflict:
+		return ClassConflict
+	case ErrNotModified:
+		return ClassNotModified
+	case ErrAborted:
+		return ClassAborted
+	case ErrOutOfRange:
+		return ClassOutOfRange
+	case ErrNotImplemented:
+		return ClassNotImplemented
+	case ErrInternal:
+		return ClassInternal
+	case ErrUnavailable:
+		return ClassUnavailable
+	case ErrDataLoss:
+		return ClassDataLoss
+	case ErrUnauthenticated:
+		return ClassUnauthenticated
+	case context.DeadlineExceeded:
+		return ClassDeadlineExceeded
+	case context.Canceled:
+		return ClassCanceled
+	default:
+		return ClassUnknown
+	}
+}
+
+// ParseClass returns the class with the given canonical name, as returned
+// by Class.String.
+func ParseClass(name string) (Class, error) {
+	for _, info := range classes {
+		if info.Name == name {
+			return info.Class, nil
+		}
+	}
+	return ClassUnknown, fmt.Errorf("unknown error class %q: %w", name, ErrInvalidArgument)
+}
+
+// Info returns the description of the class. An undefined class is
+// described as ClassUnknown.
+func (c Class) Info() ClassInfo {
+	if c >= numClasses {
+		return classes[ClassUnknown]
+	}
+	return classes[c]
+}
+
+// Err returns the error the class resolves to, such as ErrNotFound
+// for ClassNotFound.
+func (c Class) Err() error {
+	return c.Info().Err
+}
+
+// String returns the canonical name of the class, such as "NOT_FOUND".
+func (c Class) String() string {
+	if c >= numClasses {
+		return "Class(" + strconv.Itoa(int(c)) + ")"
+	}
+	return classes[c].Name
+}
+
+// MarshalText encodes the class as its canonical name.
+func (c Class) MarshalText() ([]byte, error) {
+	if c >= numClasses {
+		return nil, fmt.Errorf("invalid error class %d: %w", c, ErrInvalidArgument)
+	}
+	return []byte(classes[c].Name), nil
+}
+
+// UnmarshalText decodes the class from its canonical name.
+func (c *Class) UnmarshalText(text []byte) error {
+	parsed, err := ParseClass(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
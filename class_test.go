@@ -0,0 +1,104 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassTable(t *testing.T) {
+	for i, info := range Classes() {
+		info := info
+		t.Run(info.Name, func(t *testing.T) {
+			if info.Class != Class(i) {
+				t.Fatalf("class %d listed at index %d", info.Class, i)
+			}
+			if info.Class.String() != info.Name {
+				t.Fatalf("unexpected name %q, expected %q", info.Class.String(), info.Name)
+			}
+			if c := ClassOf(info.Err); c != info.Class {
+				t.Fatalf("sentinel %v resolved to %v", info.Err, c)
+			}
+			if c := ClassOf(fmt.Errorf("wrapped: %w", info.Err)); c != info.Class {
+				t.Fatalf("wrapped sentinel %v resolved to %v", info.Err, c)
+			}
+			parsed, err := ParseClass(info.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed != info.Class {
+				t.Fatalf("parsed %q as %v", info.Name, parsed)
+			}
+		})
+	}
+}
+
+func TestClassOf(t *testing.T) {
+	for _, tc := range []struct {
+		err   error
+		class Class
+	}{
+		{nil, ClassUnknown},
+		{errors.New("untyped"), ClassUnknown},
+		{ErrNotFound.WithMessage("custom"), ClassNotFound},
+		{testUnavailable{}, ClassUnavailable},
+		{errors.Join(ErrConflict, ErrNotFound), ClassConflict},
+	} {
+		if c := ClassOf(tc.err); c != tc.class {
+			t.Errorf("ClassOf(%v) = %v, expected %v", tc.err, c, tc.class)
+		}
+	}
+}
+
+func TestParseClassInvalid(t *testing.T) {
+	for _, name := range []string{"", "not_found", "NotFound", "Class(99)"} {
+		if _, err := ParseClass(name); !IsInvalidArgument(err) {
+			t.Errorf("expected invalid argument parsing %q, got %v", name, err)
+		}
+	}
+}
+
+func TestClassJSON(t *testing.T) {
+	type doc struct {
+		Class Class `json:"class"`
+	}
+	b, err := json.Marshal(doc{Class: ClassFailedPrecondition})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"class":"FAILED_PRECONDITION"}` {
+		t.Fatalf("unexpected encoding: %s", b)
+	}
+
+	var d doc
+	if err := json.Unmarshal([]byte(`{"class":"DEADLINE_EXCEEDED"}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Class != ClassDeadlineExceeded {
+		t.Fatalf("unexpected class %v", d.Class)
+	}
+
+	if err := json.Unmarshal([]byte(`{"class":"TEAPOT"}`), &d); err == nil {
+		t.Fatal("expected error decoding unknown class")
+	}
+	if _, err := json.Marshal(doc{Class: Class(200)}); err == nil {
+		t.Fatal("expected error encoding undefined class")
+	}
+}
@@ -0,0 +1,105 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import "strings"
+
+// FieldViolation is an invalid argument error for a single field of a
+// request. Violations of several fields may be returned together using
+// InvalidFields or errors.Join, and retrieved using FieldViolations.
+type FieldViolation struct {
+	// Field is the path to the field within the request, such as
+	// "config.mounts[0].source".
+	Field string
+
+	// Description explains why the field is invalid.
+	Description string
+
+	// Reason is an optional upper snake case identifier for the cause of
+	// the violation, such as "REQUIRED".
+	Reason string
+}
+
+func (v FieldViolation) Error() string {
+	desc := v.Description
+	if desc == "" {
+		desc = ErrInvalidArgument.Error()
+	}
+	if v.Field == "" {
+		return desc
+	}
+	return v.Field + ": " + desc
+}
+
+// InvalidParameter marks the violation as an invalid argument error
+func (FieldViolation) InvalidParameter() {}
+
+// InvalidFields is an invalid argument error for a request with one or
+// more invalid fields.
+type InvalidFields []FieldViolation
+
+func (e InvalidFields) Error() string {
+	if len(e) == 0 {
+		return ErrInvalidArgument.Error()
+	}
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = v.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// InvalidParameter marks the violations as an invalid argument error
+func (InvalidFields) InvalidParameter() {}
+
+// Err returns the violations as an error, or nil if there are none. This
+// allows validation functions to collect violations and return the result.
+func (e InvalidFields) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// FieldViolations returns all the field violations found in the error,
+// either as FieldViolation or InvalidFields errors, in the order they are
+// found in the error tree. Nil is returned if there are no violations.
+func FieldViolations(err error) InvalidFields {
+	return collectViolations[FieldViolation, InvalidFields](err)
+}
+
+// collectViolations returns the violations of type V found in the error
+// tree, either as single violations or lists of violations of type L.
+func collectViolations[V error, L ~[]V](err error) L {
+	switch e := err.(type) {
+	case V:
+		return L{e}
+	case L:
+		if len(e) > 0 {
+			return append(L(nil), e...)
+		}
+	case interface{ Unwrap() error }:
+		return collectViolations[V, L](e.Unwrap())
+	case interface{ Unwrap() []error }:
+		var violations L
+		for _, ue := range e.Unwrap() {
+			violations = append(violations, collectViolations[V, L](ue)...)
+		}
+		return violations
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestInvalidFields(t *testing.T) {
+	var fields InvalidFields
+	if fields.Err() != nil {
+		t.Fatal("expected nil error without violations")
+	}
+	fields = append(fields,
+		FieldViolation{Field: "image", Description: "must not be empty", Reason: "REQUIRED"},
+		FieldViolation{Field: "mounts[1].type", Description: `unsupported type "nfs"`},
+	)
+	err := fmt.Errorf("create container: %w", fields.Err())
+	if !IsInvalidArgument(err) || ClassOf(err) != ClassInvalidArgument {
+		t.Fatalf("expected invalid argument, got %v", ClassOf(err))
+	}
+	if msg := err.Error(); msg != `create container: image: must not be empty; mounts[1].type: unsupported type "nfs"` {
+		t.Fatalf("unexpected message %q", msg)
+	}
+	if v := FieldViolations(err); !reflect.DeepEqual(v, fields) {
+		t.Fatalf("unexpected violations %v", v)
+	}
+	var target InvalidFields
+	if !errors.As(err, &target) || len(target) != 2 {
+		t.Fatalf("errors.As should match InvalidFields: %v", err)
+	}
+}
+
+func TestFieldViolationsJoined(t *testing.T) {
+	v1 := FieldViolation{Field: "name", Description: "too long"}
+	v2 := FieldViolation{Field: "labels", Reason: "INVALID_FORMAT"}
+	err := errors.Join(v1, fmt.Errorf("runtime: %w", v2))
+	if !IsInvalidArgument(err) {
+		t.Fatalf("expected invalid argument: %v", err)
+	}
+	if msg := err.Error(); msg != "name: too long\nruntime: labels: invalid argument" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+	if v := FieldViolations(err); !reflect.DeepEqual(v, InvalidFields{v1, v2}) {
+		t.Fatalf("unexpected violations %v", v)
+	}
+	if !errors.Is(err, v2) {
+		t.Fatal("errors.Is should match the violation")
+	}
+	if FieldViolations(ErrInvalidArgument) != nil || FieldViolations(nil) != nil {
+		t.Fatal("unexpected violations")
+	}
+}
@@ -0,0 +1,56 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import "errors"
+
+// WithPublicMessage returns the error marked with a message which is safe to
+// show to remote callers, such as "image not found" for an error which
+// includes internal paths or queries. The returned error keeps the message
+// of err, so logging still shows the detailed internal message, as well as
+// its class, and unwraps to it. A nil error is returned unchanged.
+//
+// The errgrpc and errhttp packages send the public message in place of the
+// message of the error, along with the class and any reason or typed
+// errors, to callers which are not trusted with internal messages.
+func WithPublicMessage(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return publicMessage{error: err, msg: msg}
+}
+
+// PublicMessage returns the message of the error which is safe to show to
+// remote callers, as set by WithPublicMessage. When set multiple times, the
+// outermost public message is returned. False is returned if the error has
+// no public message.
+func PublicMessage(err error) (string, bool) {
+	var pm publicMessage
+	if errors.As(err, &pm) {
+		return pm.msg, true
+	}
+	return "", false
+}
+
+type publicMessage struct {
+	error
+	msg string
+}
+
+func (e publicMessage) Unwrap() error {
+	return e.error
+}
@@ -0,0 +1,46 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPublicMessage(t *testing.T) {
+	if WithPublicMessage(nil, "safe") != nil {
+		t.Fatal("expected nil error")
+	}
+	if _, ok := PublicMessage(fmt.Errorf("open /var/lib/secret: %w", ErrNotFound)); ok {
+		t.Fatal("unexpected public message")
+	}
+
+	inner := WithPublicMessage(fmt.Errorf("select * from leases where id = 'abc': %w", ErrNotFound), "lease not found")
+	err := fmt.Errorf("open /var/lib/containerd/meta.db: %w", inner)
+	if !IsNotFound(err) {
+		t.Fatalf("class not preserved: %v", err)
+	}
+	if msg := err.Error(); msg != "open /var/lib/containerd/meta.db: select * from leases where id = 'abc': not found" {
+		t.Fatalf("internal message not preserved: %q", msg)
+	}
+	if msg, ok := PublicMessage(err); !ok || msg != "lease not found" {
+		t.Fatalf("unexpected public message %q", msg)
+	}
+	if msg, _ := PublicMessage(WithPublicMessage(err, "failed to get lease")); msg != "failed to get lease" {
+		t.Fatalf("expected outermost public message, got %q", msg)
+	}
+}
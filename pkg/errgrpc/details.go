@@ -0,0 +1,298 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errgrpc
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/protoadapt"
+
+	"github.com/containerd/errdefs"
+)
+
+// Keys of the errdefs specific metadata. Only one google.rpc.ErrorInfo is
+// sent with a status, so the fields of typed errors which are not supported
+// by the standard details are carried in its metadata alongside the reason,
+// keyed by the index of the typed error within its detail.
+const (
+	// metadataPrefix is the prefix of every errdefs specific key, so that
+	// the keys are not mistaken for the metadata of the reason returned by
+	// errdefs.ReasonOf
+	metadataPrefix = ClassDomain + "/"
+
+	// classKey holds the class of an error sent with a reason when the
+	// class cannot be derived from the gRPC code, or when the reason is in
+	// the ClassDomain domain so that it is not mistaken for the class
+	classKey = metadataPrefix + "class"
+
+	// fieldsKey is followed by the index of a field violation and
+	// ".reason", the google.rpc.BadRequest detail has no reason
+	fieldsKey = metadataPrefix + "fields."
+
+	// quotasKey is followed by the index of a quota violation and
+	// ".limit", ".current" or ".max", the google.rpc.QuotaFailure detail
+	// only has the subject and description
+	quotasKey = metadataPrefix + "quotas."
+
+	// resourcesKey is followed by the index of a resource and ".class" or
+	// ".operation", which have no field in the google.rpc.ResourceInfo
+	// detail
+	resourcesKey = metadataPrefix + "resources."
+
+	// droppedKey holds the number of details dropped to fit the status in
+	// the size limit, see limitStatus
+	droppedKey = metadataPrefix + "dropped"
+)
+
+// typedDetails returns the standard error details for the typed errors
+// defined by errdefs which are found in the error, along with the metadata
+// for the fields those details do not support. Each kind of detail is added
+// once for the whole error, combining the typed errors found in the error
+// tree. The descriptions are passed through redact.
+func typedDetails(err error, redact func(string) string) ([]protoadapt.MessageV1, map[string]string) {
+	var (
+		details []protoadapt.MessageV1
+		md      = map[string]string{}
+	)
+
+	if fields := errdefs.FieldViolations(err); len(fields) > 0 {
+		br := &errdetails.BadRequest{}
+		for i, v := range fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: redact(v.Description),
+			})
+			if v.Reason != "" {
+				md[fieldsKey+strconv.Itoa(i)+".reason"] = v.Reason
+			}
+		}
+		details = append(details, br)
+	}
+
+	if preconditions := errdefs.PreconditionViolations(err); len(preconditions) > 0 {
+		pf := &errdetails.PreconditionFailure{}
+		for _, v := range preconditions {
+			pf.Violations = append(pf.Violations, &errdetails.PreconditionFailure_Violation{
+				Type:        v.Type,
+				Subject:     v.Subject,
+				Description: redact(v.Description),
+			})
+		}
+		details = append(details, pf)
+	}
+
+	if quotas := errdefs.QuotaViolations(err); len(quotas) > 0 {
+		qf := &errdetails.QuotaFailure{}
+		for i, v := range quotas {
+			qf.Violations = append(qf.Violations, &errdetails.QuotaFailure_Violation{
+				Subject:     v.Subject,
+				Description: redact(v.Description),
+			})
+			if v.Limit != "" || v.Current != 0 || v.Max != 0 {
+				key := quotasKey + strconv.Itoa(i)
+				md[key+".limit"] = v.Limit
+				md[key+".current"] = strconv.FormatInt(v.Current, 10)
+				md[key+".max"] = strconv.FormatInt(v.Max, 10)
+			}
+		}
+		details = append(details, qf)
+	}
+
+	for i, r := range errdefs.ResourceErrors(err) {
+		details = append(details, &errdetails.ResourceInfo{
+			ResourceType: r.Type,
+			ResourceName: r.Name,
+			Owner:        r.Owner,
+		})
+		key := resourcesKey + strconv.Itoa(i)
+		md[key+".class"] = errdefs.ClassOf(r).String()
+		if r.Operation != "" {
+			md[key+".operation"] = r.Operation
+		}
+	}
+
+	return details, md
+}
+
+// errorInfo returns the google.rpc.ErrorInfo detail for the error of class
+// c, carrying the reason returned by errdefs.ReasonOf along with the errdefs
+// specific metadata. Errors without a reason are sent with the ClassDomain
+// domain and the name of the class as the reason. Nil is returned when there
+// is no reason, no metadata and the class can be derived from the gRPC code.
+func errorInfo(err error, c errdefs.Class, md map[string]string) *errdetails.ErrorInfo {
+	r, ok := errdefs.ReasonOf(err)
+	if !ok {
+		if len(md) == 0 && codeClass(c.Info().GRPCCode) == c {
+			return nil
+		}
+		info := &errdetails.ErrorInfo{Domain: ClassDomain, Reason: c.String()}
+		if len(md) > 0 {
+			info.Metadata = md
+		}
+		return info
+	}
+	info := &errdetails.ErrorInfo{Domain: r.Domain, Reason: r.Reason}
+	withClass := codeClass(c.Info().GRPCCode) != c || r.Domain == ClassDomain
+	if len(md) > 0 || len(r.Metadata) > 0 || withClass {
+		info.Metadata = make(map[string]string, len(md)+len(r.Metadata)+1)
+		for k, v := range r.Metadata {
+			info.Metadata[k] = v
+		}
+		for k, v := range md {
+			info.Metadata[k] = v
+		}
+		if withClass {
+			info.Metadata[classKey] = c.String()
+		}
+	}
+	return info
+}
+
+// decodeInfo decodes a google.rpc.ErrorInfo detail added by errorInfo,
+// returning the class if it is carried by the detail and the reason, if
+// any, without the errdefs specific metadata. The errdefs specific metadata
+// is kept for decoding the typed errors. Only details in the ClassDomain
+// domain without the metadata of a reason carry the class alone, any other
+// detail is returned as the reason.
+func (t *typedErrors) decodeInfo(info *errdetails.ErrorInfo) (errdefs.Class, bool, *errdetails.ErrorInfo) {
+	var md map[string]string
+	for k, v := range info.GetMetadata() {
+		if strings.HasPrefix(k, metadataPrefix) {
+			if t.md == nil {
+				t.md = map[string]string{}
+			}
+			t.md[k] = v
+			continue
+		}
+		if md == nil {
+			md = map[string]string{}
+		}
+		md[k] = v
+	}
+	if _, withClass := info.GetMetadata()[classKey]; info.GetDomain() == ClassDomain && md == nil && !withClass {
+		if c, err := errdefs.ParseClass(info.GetReason()); err == nil {
+			return c, true, nil
+		}
+	}
+	reason := &errdetails.ErrorInfo{Domain: info.GetDomain(), Reason: info.GetReason(), Metadata: md}
+	if c, err := errdefs.ParseClass(t.md[classKey]); err == nil {
+		return c, true, reason
+	}
+	return errdefs.ClassUnknown, false, reason
+}
+
+// isTypedError returns true if the error is carried by typedDetails and
+// should not be encoded as a separate detail.
+func isTypedError(err error) bool {
+	switch err.(type) {
+	case errdefs.FieldViolation, errdefs.InvalidFields,
+		errdefs.PreconditionViolation, errdefs.FailedPreconditions,
+		errdefs.QuotaViolation, errdefs.ExceededQuotas, *errdefs.ResourceError:
+		return true
+	}
+	return false
+}
+
+// typedErrors collects the typed errors decoded from the details added by
+// typedDetails and the errdefs specific metadata of the error info.
+type typedErrors struct {
+	fields        errdefs.InvalidFields
+	preconditions errdefs.FailedPreconditions
+	quotas        errdefs.ExceededQuotas
+	resources     []*errdefs.ResourceError
+	md            map[string]string
+}
+
+// decode decodes the detail if it was added by typedDetails, returning
+// false for any other detail.
+func (t *typedErrors) decode(detail any) bool {
+	switch d := detail.(type) {
+	case *errdetails.BadRequest:
+		for _, v := range d.GetFieldViolations() {
+			t.fields = append(t.fields, errdefs.FieldViolation{
+				Field:       v.GetField(),
+				Description: v.GetDescription(),
+			})
+		}
+		return true
+	case *errdetails.PreconditionFailure:
+		for _, v := range d.GetViolations() {
+			t.preconditions = append(t.preconditions, errdefs.PreconditionViolation{
+				Type:        v.GetType(),
+				Subject:     v.GetSubject(),
+				Description: v.GetDescription(),
+			})
+		}
+		return true
+	case *errdetails.QuotaFailure:
+		for _, v := range d.GetViolations() {
+			t.quotas = append(t.quotas, errdefs.QuotaViolation{
+				Subject:     v.GetSubject(),
+				Description: v.GetDescription(),
+			})
+		}
+		return true
+	case *errdetails.ResourceInfo:
+		t.resources = append(t.resources, &errdefs.ResourceError{
+			Type:  d.GetResourceType(),
+			Name:  d.GetResourceName(),
+			Owner: d.GetOwner(),
+		})
+		return true
+	}
+	return false
+}
+
+// errors returns the decoded typed errors. Resource errors sent without
+// their class have the class error cls.
+func (t *typedErrors) errors(cls error) []error {
+	var errs []error
+	if len(t.fields) > 0 {
+		for i := range t.fields {
+			t.fields[i].Reason = t.md[fieldsKey+strconv.Itoa(i)+".reason"]
+		}
+		errs = append(errs, t.fields)
+	}
+	if len(t.preconditions) > 0 {
+		errs = append(errs, t.preconditions)
+	}
+	if len(t.quotas) > 0 {
+		for i := range t.quotas {
+			key := quotasKey + strconv.Itoa(i)
+			t.quotas[i].Limit = t.md[key+".limit"]
+			t.quotas[i].Current, _ = strconv.ParseInt(t.md[key+".current"], 10, 64)
+			t.quotas[i].Max, _ = strconv.ParseInt(t.md[key+".max"], 10, 64)
+		}
+		errs = append(errs, t.quotas)
+	}
+	for i, r := range t.resources {
+		key := resourcesKey + strconv.Itoa(i)
+		r.Err = cls
+		if c, err := errdefs.ParseClass(t.md[key+".class"]); err == nil {
+			r.Err = c.Err()
+		}
+		r.Operation = t.md[key+".operation"]
+		errs = append(errs, r)
+	}
+	if dropped, ok := t.md[droppedKey]; ok {
+		n, _ := strconv.Atoi(dropped)
+		errs = append(errs, truncatedError{dropped: n})
+	}
+	return errs
+}
@@ -0,0 +1,177 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errgrpc
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/containerd/errdefs/pkg/redact"
+)
+
+// DebugMetadataKey is the incoming metadata key a client sets to "true" to
+// request debug details, see DebugRequested.
+const DebugMetadataKey = "errdefs-debug"
+
+// Encoder converts errors to gRPC errors in the same way as ToGRPC, with
+// behavior configured once for a server using EncoderOpt options.
+type Encoder struct {
+	trusted  func(context.Context) bool
+	debug    func(context.Context) bool
+	redactor *redact.Redactor
+	maxSize  int
+}
+
+// EncoderOpt configures an Encoder
+type EncoderOpt func(*Encoder)
+
+// WithTrustedPeers sets the function deciding whether the caller of the
+// request with the given context is trusted. Trusted callers receive the
+// internal messages of errors rather than their public messages, see
+// errdefs.WithPublicMessage. Passing a function which always returns true
+// trusts every caller.
+func WithTrustedPeers(fn func(ctx context.Context) bool) EncoderOpt {
+	return func(e *Encoder) {
+		e.trusted = fn
+	}
+}
+
+// WithDebugPeers sets the function deciding whether the caller of the request
+// with the given context receives debug details. Debug details are the stack
+// traces from the stack package and the details of the wrapped and joined
+// errors, which may include process information and source paths. The
+// class, retry delay, reason and typed error details are always sent.
+//
+// Without this option every caller receives debug details, as with ToGRPC.
+// The decision can be overridden for a single call using WithDebugDetails.
+func WithDebugPeers(fn func(ctx context.Context) bool) EncoderOpt {
+	return func(e *Encoder) {
+		e.debug = fn
+	}
+}
+
+type debugKey struct{}
+
+// WithDebugDetails returns a context which overrides whether an Encoder sends
+// debug details when converting an error for the request, taking precedence
+// over the function set by WithDebugPeers.
+func WithDebugDetails(ctx context.Context, include bool) context.Context {
+	return context.WithValue(ctx, debugKey{}, include)
+}
+
+// DebugRequested returns true if the incoming metadata of the context has
+// DebugMetadataKey set to a true value. A server which only trusts some
+// callers should combine this with its own check of the caller, such as
+// whether the peer is connected over a local socket, before passing it to
+// WithDebugPeers.
+func DebugRequested(ctx context.Context) bool {
+	for _, v := range metadata.ValueFromIncomingContext(ctx, DebugMetadataKey) {
+		if b, err := strconv.ParseBool(v); err == nil && b {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRedactor sets the redactor applied to the messages of the status and
+// its nested detail statuses, the descriptions of the typed error details
+// and any detail implementing redact.Redactable, such as stack traces.
+func WithRedactor(r *redact.Redactor) EncoderOpt {
+	return func(e *Encoder) {
+		e.redactor = r
+	}
+}
+
+// WithMaxStatusSize sets the limit, in bytes, of the encoded status including
+// the message and details. Details are dropped from statuses over the limit,
+// starting with stack traces and then the statuses of wrapped and joined
+// errors, and the number dropped is recorded in the status, see
+// DetailsTruncated. The code and message are always kept. A zero size uses
+// DefaultMaxStatusSize and a negative size removes the limit.
+func WithMaxStatusSize(size int) EncoderOpt {
+	return func(e *Encoder) {
+		e.maxSize = size
+	}
+}
+
+// NewEncoder returns an encoder configured with the options
+func NewEncoder(opts ...EncoderOpt) *Encoder {
+	e := &Encoder{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+var defaultEncoder = &Encoder{}
+
+// ToGRPC maps the error to a gRPC error for the caller of the request with
+// the given context, see the package function ToGRPC.
+func (e *Encoder) ToGRPC(ctx context.Context, err error) error {
+	return e.encoding(ctx).encode(err)
+}
+
+// encoding returns the state for converting an error for the caller of the
+// request with the given context
+func (e *Encoder) encoding(ctx context.Context) encoding {
+	debug, ok := ctx.Value(debugKey{}).(bool)
+	if !ok {
+		debug = e.debug == nil || e.debug(ctx)
+	}
+	return encoding{
+		Encoder: e,
+		trusted: e.trusted != nil && e.trusted(ctx),
+		debug:   debug,
+	}
+}
+
+// encode converts the error and limits the size of the resulting status
+func (c encoding) encode(err error) error {
+	err = c.toGRPC(err)
+	if err == nil {
+		return nil
+	}
+	max := c.maxSize
+	if max == 0 {
+		max = DefaultMaxStatusSize
+	}
+	st, _ := status.FromError(err)
+	if sp := st.Proto(); proto.Size(sp) > max && max > 0 {
+		return status.ErrorProto(limitStatus(sp, max))
+	}
+	return err
+}
+
+// encoding is the state of a single conversion by an Encoder
+type encoding struct {
+	*Encoder
+
+	// trusted is set when the caller may receive internal messages
+	trusted bool
+
+	// debug is set when the caller may receive stack traces and the
+	// details of wrapped and joined errors
+	debug bool
+
+	// merged is the status of the gRPC error the details are being added
+	// to, see mergeStatus
+	merged *status.Status
+}
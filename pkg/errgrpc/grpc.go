@@ -18,7 +18,11 @@
 // and from a gRPC context.
 //
 // The functions ToGRPC and ToNative can be used to map server-side and
-// client-side errors to the correct types.
+// client-side errors to the correct types. An Encoder configures how a
+// server sends errors, such as which callers are trusted with the internal
+// messages of errors, which callers receive debug details such as stack
+// traces and how sensitive text is redacted. The interceptors apply the
+// conversions to every call of a server or client.
 package errgrpc
 
 import (
@@ -29,20 +33,29 @@ import (
 	"strconv"
 	"strings"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	spb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/protoadapt"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/containerd/typeurl/v2"
 
 	"github.com/containerd/errdefs"
 	"github.com/containerd/errdefs/pkg/internal/cause"
 	"github.com/containerd/errdefs/pkg/internal/types"
+	"github.com/containerd/errdefs/pkg/redact"
 )
 
+// ClassDomain is the domain of the google.rpc.ErrorInfo detail used to carry
+// the errdefs class of an error without a reason. The reason is set to the
+// canonical name of the class, such as "CONFLICT". The domain is reserved
+// for errdefs, reasons set by errdefs.WithReason should use another domain.
+const ClassDomain = "errdefs.containerd.io"
+
 // ToGRPC will attempt to map the error into a grpc error, from the error types
 // defined in the the errdefs package and attempign to preserve the original
 // description. Any type which does not resolve to a defined error type will
@@ -60,61 +73,266 @@ import (
 //	JoinErrors(...error) error - Used to join all previous errors
 //	CollapseError()            - Used for errors which carry information but
 //	                             should not have their error message shown.
+//
+// A gRPC status error is returned unchanged, as is the status an error
+// returned by ToNative was decoded from. When a status error is wrapped
+// or joined with other errors, the code and details of its status are kept,
+// the message of the error is used, keeping the wrapping context, and the
+// details of the other errors are added, with their reason, retry delay and
+// typed errors taking precedence over those of the status. An Encoder only
+// sends status errors unchanged when the caller may receive everything the
+// status carries, otherwise the status is sent as if it was wrapped.
+//
+// When the error has a public message, see errdefs.WithPublicMessage, the
+// public message is sent instead of the message of the error and the details
+// of the wrapped and joined errors are left out. Use an Encoder to send the
+// internal messages to trusted callers.
+//
+// The reason returned by errdefs.ReasonOf is included as the first detail
+// using a google.rpc.ErrorInfo, the only one sent with the status. When the
+// errdefs class of the error shares its gRPC code with another class, such
+// as ErrConflict sent as FailedPrecondition, the class is added to its
+// metadata, allowing ToNative to distinguish it. Errors without a reason
+// carry the class using the ClassDomain domain and the name of the class as
+// the reason. A delay set by errdefs.WithRetryAfter is included as a
+// google.rpc.RetryInfo detail. The violations returned by
+// errdefs.FieldViolations, errdefs.PreconditionViolations and
+// errdefs.QuotaViolations are included as google.rpc.BadRequest,
+// google.rpc.PreconditionFailure and google.rpc.QuotaFailure details, and
+// each errdefs.ResourceError as a google.rpc.ResourceInfo detail, with the
+// fields those details do not support in the metadata of the ErrorInfo.
+//
+// The encoded status is limited to DefaultMaxStatusSize bytes, dropping
+// stack traces and then the details of wrapped and joined errors from larger
+// statuses, see WithMaxStatusSize.
 func ToGRPC(err error) error {
+	return defaultEncoder.encoding(context.Background()).encode(err)
+}
+
+func (c encoding) toGRPC(err error) error {
 	if err == nil {
 		return nil
 	}
 
-	if _, ok := status.FromError(err); ok {
-		// error has already been mapped to grpc
-		return err
+	if st, ok := status.FromError(err); ok {
+		if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok && c.passThrough() {
+			if ne, ok := err.(*nativeError); ok {
+				// send the status the error was decoded from
+				return ne.st.Err()
+			}
+			// error has already been mapped to grpc
+			return err
+		}
+		return c.mergeStatus(err, st)
 	}
-	st := statusFromError(err)
+
+	desc, public := c.message(err)
+	st := statusFromError(err, desc)
 	if st != nil {
-		if details := errorDetails(err, false); len(details) > 0 {
-			if ds, _ := st.WithDetails(details...); ds != nil {
-				st = ds
-			}
+		if ds, _ := st.WithDetails(c.details(err, public, false)...); ds != nil {
+			st = ds
 		}
 		err = st.Err()
 	}
 	return err
 }
 
-func statusFromError(err error) *status.Status {
+// mergeStatus returns the status of the gRPC error wrapped or joined in the
+// error, as returned by status.FromError, with the details of the errors
+// around it added. The code of the original status is kept and the message
+// of the error, which includes the wrapping context, is used with the
+// message of the status in place of that of the gRPC error.
+//
+// The error info, retry delay and typed error details are regenerated, with
+// those of the errors around the status taking precedence, and the class of
+// the status. The other details of the status are only kept when the
+// details of wrapped and joined errors would be sent, see details, and are
+// passed through the redactor.
+func (c encoding) mergeStatus(err error, st *status.Status) error {
+	var gerr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &gerr) {
+		return err
+	}
+	c.merged = gerr.GRPCStatus()
+	desc, public := c.message(err)
+	if !public {
+		// Avoid repeating the code from the message of the status error
+		desc = strings.Replace(desc, c.redactor.String(gerr.(error).Error()), c.redactor.String(c.merged.Message()), 1)
+	}
+
+	inner := ToNative(gerr.(error))
+	view := err
+	if _, ok := gerr.(*nativeError); !ok {
+		// Decode the details of the status, after those of the
+		// errors around it
+		view = errors.Join(err, inner)
+	}
+	merged := status.New(st.Code(), desc)
+	if ds, _ := merged.WithDetails(c.infoDetails(view, errdefs.ClassOf(inner))...); ds != nil {
+		merged = ds
+	}
+	sp := merged.Proto()
+	for _, d := range st.Proto().GetDetails() {
+		if d, ok := c.mergedDetail(d, public); ok {
+			sp.Details = append(sp.Details, d)
+		}
+	}
+	merged = status.FromProto(sp)
+	if !public && c.debug {
+		if ds, _ := merged.WithDetails(c.errorDetails(err, true)...); ds != nil {
+			merged = ds
+		}
+	}
+	return merged.Err()
+}
+
+// passThrough returns true if gRPC status errors may be sent unchanged,
+// which is only the case when the caller may receive everything the status
+// carries, see mergeStatus
+func (c encoding) passThrough() bool {
+	return c.redactor == nil && c.debug && (c.trusted || c.Encoder.trusted == nil)
+}
+
+// mergedDetail returns the detail of a merged status to send, or false if
+// the detail is regenerated by mergeStatus or may not be sent
+func (c encoding) mergedDetail(a *anypb.Any, public bool) (*anypb.Any, bool) {
+	if d, err := a.UnmarshalNew(); err == nil {
+		switch d.(type) {
+		case *errdetails.ErrorInfo, *errdetails.RetryInfo, *errdetails.BadRequest,
+			*errdetails.PreconditionFailure, *errdetails.QuotaFailure, *errdetails.ResourceInfo:
+			return nil, false
+		}
+	}
+	if public || !c.debug {
+		return nil, false
+	}
+	return c.redactDetail(a), true
+}
+
+// redactDetail returns the detail with the redactor applied to the errors
+// implementing redact.Redactable, such as stack traces, and to the messages
+// of nested statuses
+func (c encoding) redactDetail(a *anypb.Any) *anypb.Any {
+	if c.redactor == nil {
+		return a
+	}
+	var nested spb.Status
+	if a.MessageIs(&nested) && a.UnmarshalTo(&nested) == nil {
+		nested.Message = c.redactor.String(nested.Message)
+		for i, d := range nested.Details {
+			nested.Details[i] = c.redactDetail(d)
+		}
+		if r, err := anypb.New(&nested); err == nil {
+			return r
+		}
+		return a
+	}
+
+	// Details from toProtoMessage are sent as an Any within the Any
+	var inner anypb.Any
+	if a.MessageIs(&inner) && a.UnmarshalTo(&inner) == nil {
+		v, err := typeurl.UnmarshalAny(&inner)
+		if err != nil {
+			return a
+		}
+		if rd, ok := v.(redact.Redactable); ok {
+			if pm := toProtoMessage(c.redactor.Detail(rd.(error))); pm != nil {
+				if r, err := anypb.New(protoadapt.MessageV2Of(pm)); err == nil {
+					return r
+				}
+			}
+		}
+	}
+	return a
+}
+
+// message returns the redacted message to send for the error and whether
+// it is the public message of the error
+func (c encoding) message(err error) (string, bool) {
+	desc, public := "", false
+	if !c.trusted {
+		desc, public = errdefs.PublicMessage(err)
+	}
+	if !public {
+		desc = err.Error()
+	}
+	return c.redactor.String(desc), public
+}
+
+// details returns the details for the error, starting with the error info
+// when there is one. The details of the wrapped and joined errors are only
+// included when the message is not public, see errorDetails for
+// firstIncluded.
+func (c encoding) details(err error, public, firstIncluded bool) []protoadapt.MessageV1 {
+	details := c.infoDetails(err, errdefs.ClassOf(err))
+	if !public && c.debug {
+		// The details of the wrapped and joined errors may expose
+		// what the public message is hiding.
+		details = append(details, c.errorDetails(err, firstIncluded)...)
+	}
+	return details
+}
+
+// infoDetails returns the error info, with the class cls, followed by the
+// retry delay and typed error details for the error
+func (c encoding) infoDetails(err error, cls errdefs.Class) []protoadapt.MessageV1 {
+	var details []protoadapt.MessageV1
+	typed, md := typedDetails(err, c.redactor.String)
+	if info := errorInfo(err, cls, md); info != nil {
+		details = append(details, info)
+	}
+	if d, ok := errdefs.RetryAfter(err); ok {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(d)})
+	}
+	return append(details, typed...)
+}
+
+// codeClass returns the class ToNative derives from the gRPC code when the
+// status does not carry the class, which is the first class sent with the
+// code.
+func codeClass(code uint32) errdefs.Class {
+	for _, info := range errdefs.Classes() {
+		if info.GRPCCode == code {
+			return info.Class
+		}
+	}
+	return errdefs.ClassUnknown
+}
+
+func statusFromError(err error, desc string) *status.Status {
 	switch errdefs.Resolve(err) {
 	case errdefs.ErrInvalidArgument:
-		return status.New(codes.InvalidArgument, err.Error())
+		return status.New(codes.InvalidArgument, desc)
 	case errdefs.ErrNotFound:
-		return status.New(codes.NotFound, err.Error())
+		return status.New(codes.NotFound, desc)
 	case errdefs.ErrAlreadyExists:
-		return status.New(codes.AlreadyExists, err.Error())
+		return status.New(codes.AlreadyExists, desc)
 	case errdefs.ErrPermissionDenied:
-		return status.New(codes.PermissionDenied, err.Error())
+		return status.New(codes.PermissionDenied, desc)
 	case errdefs.ErrResourceExhausted:
-		return status.New(codes.ResourceExhausted, err.Error())
+		return status.New(codes.ResourceExhausted, desc)
 	case errdefs.ErrFailedPrecondition, errdefs.ErrConflict, errdefs.ErrNotModified:
-		return status.New(codes.FailedPrecondition, err.Error())
+		return status.New(codes.FailedPrecondition, desc)
 	case errdefs.ErrAborted:
-		return status.New(codes.Aborted, err.Error())
+		return status.New(codes.Aborted, desc)
 	case errdefs.ErrOutOfRange:
-		return status.New(codes.OutOfRange, err.Error())
+		return status.New(codes.OutOfRange, desc)
 	case errdefs.ErrNotImplemented:
-		return status.New(codes.Unimplemented, err.Error())
+		return status.New(codes.Unimplemented, desc)
 	case errdefs.ErrInternal:
-		return status.New(codes.Internal, err.Error())
+		return status.New(codes.Internal, desc)
 	case errdefs.ErrUnavailable:
-		return status.New(codes.Unavailable, err.Error())
+		return status.New(codes.Unavailable, desc)
 	case errdefs.ErrDataLoss:
-		return status.New(codes.DataLoss, err.Error())
+		return status.New(codes.DataLoss, desc)
 	case errdefs.ErrUnauthenticated:
-		return status.New(codes.Unauthenticated, err.Error())
+		return status.New(codes.Unauthenticated, desc)
 	case context.DeadlineExceeded:
-		return status.New(codes.DeadlineExceeded, err.Error())
+		return status.New(codes.DeadlineExceeded, desc)
 	case context.Canceled:
-		return status.New(codes.Canceled, err.Error())
+		return status.New(codes.Canceled, desc)
 	case errdefs.ErrUnknown:
-		return status.New(codes.Unknown, err.Error())
+		return status.New(codes.Unknown, desc)
 	}
 	return nil
 }
@@ -131,15 +349,24 @@ func statusFromError(err error) *status.Status {
 // The intent is that when re-applying the errors to create a single error, the
 // results of calls to `Error()`, `errors.Is`, `errors.As`, and "%+v" formatting
 // is the same as the original error.
-func errorDetails(err error, firstIncluded bool) []protoadapt.MessageV1 {
+func (c encoding) errorDetails(err error, firstIncluded bool) []protoadapt.MessageV1 {
+	if firstIncluded && isTypedError(err) {
+		// Carried by the details from typedDetails
+		return nil
+	}
+	if gs, ok := err.(interface{ GRPCStatus() *status.Status }); ok && c.merged != nil && gs.GRPCStatus() == c.merged {
+		// The status the details are added to
+		return nil
+	}
+
 	switch uerr := err.(type) {
 	case interface{ Unwrap() error }:
-		details := errorDetails(uerr.Unwrap(), firstIncluded)
+		details := c.errorDetails(uerr.Unwrap(), firstIncluded)
 
 		// If the type is able to wrap, then include if proto
 		if _, ok := err.(interface{ WrapError(error) error }); ok {
 			// Get proto message
-			if protoErr := toProtoMessage(err); protoErr != nil {
+			if protoErr := toProtoMessage(c.redactor.Detail(err)); protoErr != nil {
 				details = append(details, protoErr)
 			}
 		}
@@ -148,12 +375,12 @@ func errorDetails(err error, firstIncluded bool) []protoadapt.MessageV1 {
 	case interface{ Unwrap() []error }:
 		var details []protoadapt.MessageV1
 		for i, e := range uerr.Unwrap() {
-			details = append(details, errorDetails(e, firstIncluded || i > 0)...)
+			details = append(details, c.errorDetails(e, firstIncluded || i > 0)...)
 		}
 
 		if _, ok := err.(interface{ JoinErrors(...error) error }); ok {
 			// Get proto message
-			if protoErr := toProtoMessage(err); protoErr != nil {
+			if protoErr := toProtoMessage(c.redactor.Detail(err)); protoErr != nil {
 				details = append(details, protoErr)
 			}
 		}
@@ -161,10 +388,10 @@ func errorDetails(err error, firstIncluded bool) []protoadapt.MessageV1 {
 	}
 
 	if firstIncluded {
-		if protoErr := toProtoMessage(err); protoErr != nil {
+		if protoErr := toProtoMessage(c.redactor.Detail(err)); protoErr != nil {
 			return []protoadapt.MessageV1{protoErr}
 		}
-		if gs, ok := status.FromError(ToGRPC(err)); ok {
+		if gs, ok := status.FromError(c.toGRPC(err)); ok {
 			return []protoadapt.MessageV1{gs.Proto()}
 		}
 		// TODO: Else include unknown extra error type?
@@ -205,77 +432,120 @@ func ToGRPCf(err error, format string, args ...interface{}) error {
 // ToNative returns the underlying error from a grpc service based on the grpc
 // error code. The grpc details are used to add wrap the error in more context
 // or support multiple errors.
+//
+// When the status carries the errdefs class as added by ToGRPC, that class
+// is used instead of the one derived from the code. The delay of a
+// google.rpc.RetryInfo detail is returned by errdefs.RetryAfter on the error
+// and the reason of the first google.rpc.ErrorInfo, unless it only carries
+// the class, by errdefs.ReasonOf.
+// The violations of google.rpc.BadRequest, google.rpc.PreconditionFailure and
+// google.rpc.QuotaFailure details are returned by errdefs.FieldViolations,
+// errdefs.PreconditionViolations and errdefs.QuotaViolations, and
+// google.rpc.ResourceInfo details are restored as errdefs.ResourceError.
+// Whether details were dropped by the sender to limit the size of the
+// status is returned by DetailsTruncated.
+//
+// The error returned for a gRPC status error remembers the status, which is
+// returned by its GRPCStatus method and sent unchanged by ToGRPC, allowing
+// errors to be proxied without losing details which cannot be decoded.
 func ToNative(err error) error {
 	if err == nil {
 		return nil
 	}
 
+	if _, ok := err.(*nativeError); ok {
+		// already decoded
+		return err
+	}
+
 	s, isGRPC := status.FromError(err)
+	_, direct := err.(interface{ GRPCStatus() *status.Status })
 
 	var (
-		desc string
-		code codes.Code
+		desc    string
+		code    codes.Code
+		details []any
 	)
 
 	if isGRPC {
 		desc = s.Message()
 		code = s.Code()
+		details = s.Details()
 	} else {
 		desc = err.Error()
 		code = codes.Unknown
 	}
 
-	var cls error // divide these into error classes, becomes the cause
-
-	switch code {
-	case codes.InvalidArgument:
-		cls = errdefs.ErrInvalidArgument
-	case codes.AlreadyExists:
-		cls = errdefs.ErrAlreadyExists
-	case codes.NotFound:
-		cls = errdefs.ErrNotFound
-	case codes.Unavailable:
-		cls = errdefs.ErrUnavailable
-	case codes.FailedPrecondition:
-		// TODO: Has suffix is not sufficient for conflict and not modified
-		// Message should start with ": " or be at beginning of a line
-		// Message should end with ": " or be at the end of a line
-		// Compile a regex
-		if desc == errdefs.ErrConflict.Error() || strings.HasSuffix(desc, ": "+errdefs.ErrConflict.Error()) {
-			cls = errdefs.ErrConflict
-		} else if desc == errdefs.ErrNotModified.Error() || strings.HasSuffix(desc, ": "+errdefs.ErrNotModified.Error()) {
-			cls = errdefs.ErrNotModified
-		} else {
-			cls = errdefs.ErrFailedPrecondition
-		}
-	case codes.Unimplemented:
-		cls = errdefs.ErrNotImplemented
-	case codes.Canceled:
-		cls = context.Canceled
-	case codes.DeadlineExceeded:
-		cls = context.DeadlineExceeded
-	case codes.Aborted:
-		cls = errdefs.ErrAborted
-	case codes.Unauthenticated:
-		cls = errdefs.ErrUnauthenticated
-	case codes.PermissionDenied:
-		cls = errdefs.ErrPermissionDenied
-	case codes.Internal:
-		cls = errdefs.ErrInternal
-	case codes.DataLoss:
-		cls = errdefs.ErrDataLoss
-	case codes.OutOfRange:
-		cls = errdefs.ErrOutOfRange
-	case codes.ResourceExhausted:
-		cls = errdefs.ErrResourceExhausted
-	default:
-		if idx := strings.LastIndex(desc, cause.UnexpectedStatusPrefix); idx > 0 {
-			if status, uerr := strconv.Atoi(desc[idx+len(cause.UnexpectedStatusPrefix):]); uerr == nil && status >= 200 && status < 600 {
-				cls = cause.ErrUnexpectedStatus{Status: status}
+	var (
+		cls    error // divide these into error classes, becomes the cause
+		reason *errdetails.ErrorInfo
+		typed  typedErrors
+	)
+
+	for i, a := range details {
+		if info, ok := a.(*errdetails.ErrorInfo); ok {
+			c, hasClass, r := typed.decodeInfo(info)
+			if hasClass && c != errdefs.ClassUnknown {
+				cls = c.Err()
 			}
+			reason = r
+			details = append(details[:i:i], details[i+1:]...)
+			break
 		}
-		if cls == nil {
-			cls = errdefs.ErrUnknown
+	}
+
+	if cls == nil {
+		switch code {
+		case codes.InvalidArgument:
+			cls = errdefs.ErrInvalidArgument
+		case codes.AlreadyExists:
+			cls = errdefs.ErrAlreadyExists
+		case codes.NotFound:
+			cls = errdefs.ErrNotFound
+		case codes.Unavailable:
+			cls = errdefs.ErrUnavailable
+		case codes.FailedPrecondition:
+			// Only used for peers which do not send the class in the details.
+			// TODO: Has suffix is not sufficient for conflict and not modified
+			// Message should start with ": " or be at beginning of a line
+			// Message should end with ": " or be at the end of a line
+			// Compile a regex
+			if desc == errdefs.ErrConflict.Error() || strings.HasSuffix(desc, ": "+errdefs.ErrConflict.Error()) {
+				cls = errdefs.ErrConflict
+			} else if desc == errdefs.ErrNotModified.Error() || strings.HasSuffix(desc, ": "+errdefs.ErrNotModified.Error()) {
+				cls = errdefs.ErrNotModified
+			} else {
+				cls = errdefs.ErrFailedPrecondition
+			}
+		case codes.Unimplemented:
+			cls = errdefs.ErrNotImplemented
+		case codes.Canceled:
+			cls = context.Canceled
+		case codes.DeadlineExceeded:
+			cls = context.DeadlineExceeded
+		case codes.Aborted:
+			cls = errdefs.ErrAborted
+		case codes.Unauthenticated:
+			cls = errdefs.ErrUnauthenticated
+		case codes.PermissionDenied:
+			cls = errdefs.ErrPermissionDenied
+		case codes.Internal:
+			cls = errdefs.ErrInternal
+		case codes.DataLoss:
+			cls = errdefs.ErrDataLoss
+		case codes.OutOfRange:
+			cls = errdefs.ErrOutOfRange
+		case codes.ResourceExhausted:
+			cls = errdefs.ErrResourceExhausted
+		default:
+			if idx := strings.LastIndex(desc, cause.UnexpectedStatusPrefix); idx > 0 {
+				if status, uerr := strconv.Atoi(desc[idx+len(cause.UnexpectedStatusPrefix):]); uerr == nil && status >= 200 && status < 600 {
+					cls = cause.ErrUnexpectedStatus{Status: status}
+				}
+			}
+			if cls == nil {
+				cls = errdefs.ErrUnknown
+			}
 		}
 	}
 
@@ -291,10 +561,21 @@ func ToNative(err error) error {
 	}
 
 	if isGRPC {
-		errs := []error{err}
-		for _, a := range s.Details() {
+		var (
+			errs       = []error{err}
+			retryDelay *durationpb.Duration
+		)
+		for _, a := range details {
 			var derr error
 
+			if ri, ok := a.(*errdetails.RetryInfo); ok {
+				retryDelay = ri.GetRetryDelay()
+				continue
+			}
+			if typed.decode(a) {
+				continue
+			}
+
 			// First decode error if needed
 			if s, ok := a.(*spb.Status); ok {
 				derr = ToNative(status.ErrorProto(s))
@@ -333,11 +614,43 @@ func ToNative(err error) error {
 		} else {
 			err = errs[0]
 		}
+		if terrs := typed.errors(cls); len(terrs) > 0 {
+			err = types.CollapsedError(err, terrs...)
+		}
+		if retryDelay != nil {
+			err = errdefs.WithRetryAfter(err, retryDelay.AsDuration())
+		}
+		if reason != nil {
+			err = errdefs.WithReason(err, reason.GetDomain(), reason.GetReason(), reason.GetMetadata())
+		}
+		if direct {
+			err = &nativeError{error: err, st: s}
+		}
 	}
 
 	return err
 }
 
+// nativeError is an error decoded by ToNative which remembers the status it
+// was decoded from, so that ToGRPC can send the same status again.
+type nativeError struct {
+	error
+	st *status.Status
+}
+
+func (e *nativeError) Unwrap() error {
+	return e.error
+}
+
+// GRPCStatus returns the status the error was decoded from
+func (e *nativeError) GRPCStatus() *status.Status {
+	return e.st
+}
+
+func (e *nativeError) Format(s fmt.State, verb rune) {
+	fmt.Fprintf(s, fmt.FormatString(s, verb), e.error)
+}
+
 // rebaseMessage removes the repeats for an error at the end of an error
 // string. This will happen when taking an error over grpc then remapping it.
 //
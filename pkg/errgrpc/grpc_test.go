@@ -20,11 +20,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/containerd/typeurl/v2"
 
@@ -42,6 +49,15 @@ func TestGRPCNilInput(t *testing.T) {
 	}
 }
 
+func TestGRPCClassTable(t *testing.T) {
+	for _, info := range errdefs.Classes() {
+		st, _ := status.FromError(ToGRPC(info.Err))
+		if st.Code() != codes.Code(info.GRPCCode) {
+			t.Errorf("%s: ToGRPC returned code %v, class table has %v", info.Name, st.Code(), codes.Code(info.GRPCCode))
+		}
+	}
+}
+
 func TestGRPCRoundTrip(t *testing.T) {
 	errShouldLeaveAlone := errors.New("unknown to package")
 
@@ -149,6 +165,16 @@ func TestGRPCRoundTrip(t *testing.T) {
 			cause: errdefs.ErrNotModified,
 			str:   "everything is the same: not modified",
 		},
+		{
+			input: errdefs.ErrConflict.WithMessage("lease is held"),
+			cause: errdefs.ErrConflict,
+			str:   "lease is held",
+		},
+		{
+			input: fmt.Errorf("conflict detected in %q: %w", "layer", errdefs.ErrNotModified.WithMessage("digest unchanged")),
+			cause: errdefs.ErrNotModified,
+			str:   `conflict detected in "layer": digest unchanged`,
+		},
 		{
 			input: fmt.Errorf("odd HTTP response: %w", errhttp.ToNative(418)),
 			cause: cause.ErrUnexpectedStatus{Status: 418},
@@ -177,6 +203,52 @@ func TestGRPCRoundTrip(t *testing.T) {
 	}
 }
 
+func TestGRPCClassDetail(t *testing.T) {
+	gerr := ToGRPC(fmt.Errorf("checking: %w", errdefs.ErrConflict.WithMessage("in use")))
+	st, _ := status.FromError(gerr)
+	if st.Code() != codes.FailedPrecondition {
+		t.Fatalf("unexpected code %v", st.Code())
+	}
+	details := st.Details()
+	if len(details) == 0 {
+		t.Fatal("expected class detail")
+	}
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	if !ok {
+		t.Fatalf("unexpected first detail %T", details[0])
+	}
+	if info.GetDomain() != ClassDomain || info.GetReason() != "CONFLICT" {
+		t.Fatalf("unexpected class detail %v", info)
+	}
+
+	// Classes with their own code are sent without details, which older
+	// peers would report as additional errors
+	for _, err := range []error{errdefs.ErrNotFound.WithMessage("image x"), errdefs.ErrFailedPrecondition, errdefs.ErrUnknown} {
+		st, _ := status.FromError(ToGRPC(err))
+		if len(st.Details()) != 0 {
+			t.Errorf("%v: unexpected details %v", err, st.Details())
+		}
+		if nerr := ToNative(st.Err()); errdefs.ClassOf(nerr) != errdefs.ClassOf(err) || nerr.Error() != err.Error() {
+			t.Errorf("%v: unexpected error %v", err, nerr)
+		}
+	}
+
+	// Peers which do not send the class fall back to the message
+	for _, tc := range []struct {
+		msg   string
+		cause error
+	}{
+		{"something: conflict", errdefs.ErrConflict},
+		{"not modified", errdefs.ErrNotModified},
+		{"conflict: in use", errdefs.ErrFailedPrecondition},
+	} {
+		nerr := ToNative(status.Error(codes.FailedPrecondition, tc.msg))
+		if !errors.Is(nerr, tc.cause) {
+			t.Errorf("%q: expected %v, got %v", tc.msg, tc.cause, nerr)
+		}
+	}
+}
+
 type TestError struct {
 	Value string `json:"value"`
 }
@@ -185,6 +257,320 @@ func (*TestError) Error() string {
 	return "test error"
 }
 
+func TestGRPCRetryInfo(t *testing.T) {
+	err := errdefs.WithRetryAfter(fmt.Errorf("pull quota: %w", errdefs.ErrResourceExhausted), 90*time.Second)
+	gerr := ToGRPC(err)
+	st, _ := status.FromError(gerr)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("unexpected code %v", st.Code())
+	}
+	var ri *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		if r, ok := d.(*errdetails.RetryInfo); ok {
+			ri = r
+		}
+	}
+	if ri == nil || ri.GetRetryDelay().AsDuration() != 90*time.Second {
+		t.Fatalf("expected retry info detail, got %v", st.Details())
+	}
+
+	nerr := ToNative(gerr)
+	if !errdefs.IsResourceExhausted(nerr) || nerr.Error() != err.Error() {
+		t.Fatalf("unexpected error %v", nerr)
+	}
+	if d, ok := errdefs.RetryAfter(nerr); !ok || d != 90*time.Second {
+		t.Fatalf("unexpected retry after %v, %t", d, ok)
+	}
+
+	// Retry info sent by other servers, without the class detail
+	st, _ = status.New(codes.Unavailable, "overloaded").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Second)})
+	nerr = ToNative(st.Err())
+	if !errdefs.IsUnavailable(nerr) || nerr.Error() != "overloaded" {
+		t.Fatalf("unexpected error %v", nerr)
+	}
+	if d, ok := errdefs.RetryAfter(nerr); !ok || d != time.Second {
+		t.Fatalf("unexpected retry after %v, %t", d, ok)
+	}
+
+	if _, ok := errdefs.RetryAfter(ToNative(ToGRPC(errdefs.ErrUnavailable))); ok {
+		t.Fatal("unexpected retry after without retry info")
+	}
+}
+
+func TestGRPCFieldViolations(t *testing.T) {
+	fields := errdefs.InvalidFields{
+		{Field: "image", Description: "must not be empty", Reason: "REQUIRED"},
+		{Field: "mounts[1].type", Description: `unsupported type "nfs"`},
+	}
+	for _, err := range []error{
+		fmt.Errorf("create container: %w", fields),
+		errors.Join(fields[0], fmt.Errorf("mounts: %w", fields[1])),
+	} {
+		gerr := ToGRPC(err)
+		st, _ := status.FromError(gerr)
+		if st.Code() != codes.InvalidArgument {
+			t.Fatalf("unexpected code %v", st.Code())
+		}
+		var br *errdetails.BadRequest
+		for _, d := range st.Details() {
+			switch d := d.(type) {
+			case *errdetails.BadRequest:
+				br = d
+			case *spb.Status:
+				t.Fatalf("violations should not be sent as nested status: %v", d)
+			}
+		}
+		if len(br.GetFieldViolations()) != 2 || br.GetFieldViolations()[1].GetField() != "mounts[1].type" {
+			t.Fatalf("unexpected bad request detail %v", br)
+		}
+
+		for _, nerr := range []error{ToNative(gerr), ToNative(ToGRPC(ToNative(gerr)))} {
+			if !errdefs.IsInvalidArgument(nerr) || nerr.Error() != err.Error() {
+				t.Fatalf("unexpected error %v", nerr)
+			}
+			if v := errdefs.FieldViolations(nerr); !reflect.DeepEqual(v, fields) {
+				t.Fatalf("unexpected violations %#v", v)
+			}
+		}
+	}
+
+	// Bad request sent by other servers
+	st, _ := status.New(codes.InvalidArgument, "invalid name").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "name", Description: "invalid name"}},
+	})
+	nerr := ToNative(st.Err())
+	if v := errdefs.FieldViolations(nerr); len(v) != 1 || v[0].Field != "name" || nerr.Error() != "invalid name" {
+		t.Fatalf("unexpected error %v with violations %v", nerr, v)
+	}
+}
+
+func TestGRPCPreconditionFailure(t *testing.T) {
+	preconditions := errdefs.FailedPreconditions{
+		{Type: "IN_USE", Subject: "snapshot/sha256:abc", Description: "in use by 3 containers"},
+		{Type: "LEASE_MISSING", Subject: "lease/pull-1", Description: "lease not found"},
+	}
+	err := fmt.Errorf("remove snapshot: %w", preconditions)
+	gerr := ToGRPC(err)
+	st, _ := status.FromError(gerr)
+	if st.Code() != codes.FailedPrecondition {
+		t.Fatalf("unexpected code %v", st.Code())
+	}
+	var pf *errdetails.PreconditionFailure
+	for _, d := range st.Details() {
+		if d, ok := d.(*errdetails.PreconditionFailure); ok {
+			pf = d
+		}
+	}
+	if len(pf.GetViolations()) != 2 || pf.GetViolations()[0].GetType() != "IN_USE" {
+		t.Fatalf("unexpected precondition failure detail %v", pf)
+	}
+
+	nerr := ToNative(gerr)
+	if !errdefs.IsFailedPrecondition(nerr) || errdefs.IsConflict(nerr) || nerr.Error() != err.Error() {
+		t.Fatalf("unexpected error %v", nerr)
+	}
+	var target errdefs.FailedPreconditions
+	if !errors.As(nerr, &target) || !reflect.DeepEqual(target, preconditions) {
+		t.Fatalf("errors.As should match the violations: %#v", target)
+	}
+	if v := errdefs.PreconditionViolations(ToNative(ToGRPC(nerr))); !reflect.DeepEqual(v, preconditions) {
+		t.Fatalf("unexpected violations after second round trip %#v", v)
+	}
+}
+
+func TestGRPCQuotaFailure(t *testing.T) {
+	quotas := errdefs.ExceededQuotas{
+		{Subject: "namespace:default", Limit: "snapshots", Current: 100, Max: 100},
+		{Subject: "node:worker-1", Description: "no capacity for task"},
+	}
+	err := errdefs.WithRetryAfter(fmt.Errorf("schedule: %w", quotas), time.Minute)
+	gerr := ToGRPC(err)
+	st, _ := status.FromError(gerr)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("unexpected code %v", st.Code())
+	}
+	var qf *errdetails.QuotaFailure
+	for _, d := range st.Details() {
+		if d, ok := d.(*errdetails.QuotaFailure); ok {
+			qf = d
+		}
+	}
+	if len(qf.GetViolations()) != 2 || qf.GetViolations()[0].GetSubject() != "namespace:default" {
+		t.Fatalf("unexpected quota failure detail %v", qf)
+	}
+
+	for _, nerr := range []error{ToNative(gerr), ToNative(ToGRPC(ToNative(gerr)))} {
+		if !errdefs.IsResourceExhausted(nerr) || nerr.Error() != err.Error() {
+			t.Fatalf("unexpected error %v", nerr)
+		}
+		if v := errdefs.QuotaViolations(nerr); !reflect.DeepEqual(v, quotas) {
+			t.Fatalf("unexpected violations %#v", v)
+		}
+		if d, _ := errdefs.RetryAfter(nerr); d != time.Minute {
+			t.Fatalf("unexpected retry after %v", d)
+		}
+	}
+
+	// Quota failure sent by other servers
+	st, _ = status.New(codes.ResourceExhausted, "quota exceeded").WithDetails(&errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{Subject: "project:x", Description: "daily limit"}},
+	})
+	var target errdefs.ExceededQuotas
+	if nerr := ToNative(st.Err()); !errors.As(nerr, &target) || target[0].Subject != "project:x" || target[0].Max != 0 {
+		t.Fatalf("unexpected error %v with violations %#v", nerr, target)
+	}
+}
+
+func TestGRPCResourceInfo(t *testing.T) {
+	image := errdefs.ResourceNotFound("image", "docker.io/library/redis:latest")
+	image.Owner = "default"
+	image.Operation = "get"
+	lease := errdefs.ResourceAlreadyExists("lease", "pull-1")
+
+	for _, err := range []error{
+		fmt.Errorf("pull: %w", image),
+		errors.Join(fmt.Errorf("pull: %w", image), lease),
+	} {
+		gerr := ToGRPC(err)
+		st, _ := status.FromError(gerr)
+		if st.Code() != codes.NotFound {
+			t.Fatalf("unexpected code %v", st.Code())
+		}
+		var infos []*errdetails.ResourceInfo
+		for _, d := range st.Details() {
+			switch d := d.(type) {
+			case *errdetails.ResourceInfo:
+				infos = append(infos, d)
+			case *spb.Status:
+				t.Fatalf("resource errors should not be sent as nested status: %v", d)
+			}
+		}
+		if len(infos) == 0 || infos[0].GetResourceType() != "image" || infos[0].GetOwner() != "default" {
+			t.Fatalf("unexpected resource info %v", infos)
+		}
+
+		for _, nerr := range []error{ToNative(gerr), ToNative(ToGRPC(ToNative(gerr)))} {
+			if !errdefs.IsNotFound(nerr) || nerr.Error() != err.Error() {
+				t.Fatalf("unexpected error %v", nerr)
+			}
+			var target *errdefs.ResourceError
+			if !errors.As(nerr, &target) || !reflect.DeepEqual(target, image) {
+				t.Fatalf("errors.As should match the resource: %#v", target)
+			}
+			if res := errdefs.ResourceErrors(nerr); len(res) != len(infos) || !reflect.DeepEqual(res, errdefs.ResourceErrors(err)) {
+				t.Fatalf("unexpected resource errors %v", res)
+			}
+		}
+	}
+
+	// Resource info sent by other servers takes the class of the status
+	st, _ := status.New(codes.PermissionDenied, "access denied").WithDetails(&errdetails.ResourceInfo{ResourceType: "bucket", ResourceName: "layers"})
+	var target *errdefs.ResourceError
+	if nerr := ToNative(st.Err()); !errors.As(nerr, &target) || target.Name != "layers" || !errdefs.IsPermissionDenied(target) {
+		t.Fatalf("unexpected error %v with resource %#v", nerr, target)
+	}
+}
+
+func TestGRPCReason(t *testing.T) {
+	md := map[string]string{"lease": "pull-1"}
+	err := fmt.Errorf("commit: %w", errdefs.WithReason(errdefs.ErrFailedPrecondition.WithMessage("lease expired"), "containerd.io", "LEASE_EXPIRED", md))
+	gerr := ToGRPC(err)
+	st, _ := status.FromError(gerr)
+	var infos []*errdetails.ErrorInfo
+	for _, d := range st.Details() {
+		if d, ok := d.(*errdetails.ErrorInfo); ok {
+			infos = append(infos, d)
+		}
+	}
+	if len(infos) != 1 || infos[0].GetReason() != "LEASE_EXPIRED" {
+		t.Fatalf("unexpected error info details %v", infos)
+	}
+
+	for _, nerr := range []error{ToNative(gerr), ToNative(ToGRPC(ToNative(gerr)))} {
+		if !errdefs.IsFailedPrecondition(nerr) || nerr.Error() != err.Error() {
+			t.Fatalf("unexpected error %v", nerr)
+		}
+		r, ok := errdefs.ReasonOf(nerr)
+		if !ok || r.Domain != "containerd.io" || r.Reason != "LEASE_EXPIRED" || !reflect.DeepEqual(r.Metadata, md) {
+			t.Fatalf("unexpected reason %+v", r)
+		}
+	}
+
+	// A single error info carries the class and the fields of typed errors
+	err = errdefs.WithReason(errors.Join(
+		fmt.Errorf("blob in use: %w", errdefs.ErrConflict),
+		errdefs.InvalidFields{{Field: "ref", Description: "empty", Reason: "REQUIRED"}},
+		errdefs.ExceededQuotas{{Subject: "blobs", Limit: "count", Current: 10, Max: 10}},
+		&errdefs.ResourceError{Type: "blob", Name: "sha256:abc", Operation: "delete", Err: errdefs.ErrConflict},
+	), "containerd.io", "BLOB_LOCKED", md)
+	gerr = ToGRPC(err)
+	st, _ = status.FromError(gerr)
+	infos = nil
+	for _, d := range st.Details() {
+		if d, ok := d.(*errdetails.ErrorInfo); ok {
+			infos = append(infos, d)
+		}
+	}
+	if _, first := st.Details()[0].(*errdetails.ErrorInfo); !first || len(infos) != 1 || infos[0].GetReason() != "BLOB_LOCKED" {
+		t.Fatalf("expected a single error info first, got %v", st.Details())
+	}
+	nerr := ToNative(gerr)
+	if errdefs.ClassOf(nerr) != errdefs.ClassConflict {
+		t.Fatalf("unexpected class %v", errdefs.ClassOf(nerr))
+	}
+	if r, _ := errdefs.ReasonOf(nerr); r.Reason != "BLOB_LOCKED" || !reflect.DeepEqual(r.Metadata, md) {
+		t.Fatalf("unexpected reason %+v", r)
+	}
+	if fields := errdefs.FieldViolations(nerr); len(fields) != 1 || fields[0].Reason != "REQUIRED" {
+		t.Fatalf("unexpected field violations %v", fields)
+	}
+	if quotas := errdefs.QuotaViolations(nerr); len(quotas) != 1 || quotas[0].Limit != "count" || quotas[0].Max != 10 {
+		t.Fatalf("unexpected quota violations %v", quotas)
+	}
+	if resources := errdefs.ResourceErrors(nerr); len(resources) != 1 || resources[0].Operation != "delete" || !errdefs.IsConflict(resources[0]) {
+		t.Fatalf("unexpected resource errors %v", resources)
+	}
+
+	// Reasons which match the names of classes
+	for _, domain := range []string{"containerd.io", ClassDomain} {
+		for _, md := range []map[string]string{nil, {"lease": "x"}} {
+			nerr := ToNative(ToGRPC(errdefs.WithReason(errdefs.ErrConflict, domain, "ABORTED", md)))
+			if r, ok := errdefs.ReasonOf(nerr); !ok || r.Domain != domain || r.Reason != "ABORTED" || !reflect.DeepEqual(r.Metadata, md) {
+				t.Fatalf("%s %v: unexpected reason %+v", domain, md, r)
+			}
+			if c := errdefs.ClassOf(nerr); c != errdefs.ClassConflict {
+				t.Fatalf("%s %v: unexpected class %v", domain, md, c)
+			}
+		}
+	}
+
+	// Reasons which match the names used for typed error details
+	nerr = ToNative(ToGRPC(errdefs.WithReason(errdefs.ErrNotFound, "containerd.io", "RESOURCES", nil)))
+	if r, _ := errdefs.ReasonOf(nerr); r.Reason != "RESOURCES" || errdefs.ResourceErrors(nerr) != nil {
+		t.Fatalf("unexpected reason %+v for %v", r, nerr)
+	}
+
+	// Error info sent by other servers
+	st, _ = status.New(codes.PermissionDenied, "api disabled").WithDetails(&errdetails.ErrorInfo{
+		Domain: "googleapis.com",
+		Reason: "API_DISABLED",
+	})
+	nerr = ToNative(st.Err())
+	if r, ok := errdefs.ReasonOf(nerr); !ok || r.Reason != "API_DISABLED" || !errdefs.IsPermissionDenied(nerr) || nerr.Error() != "api disabled" {
+		t.Fatalf("unexpected error %v with reason %+v", nerr, r)
+	}
+
+	// Only the first error info is decoded
+	st, _ = status.New(codes.FailedPrecondition, "busy").WithDetails(
+		&errdetails.ErrorInfo{Domain: ClassDomain, Reason: "CONFLICT"},
+		&errdetails.ErrorInfo{Domain: "googleapis.com", Reason: "API_DISABLED"},
+	)
+	nerr = ToNative(st.Err())
+	if r, ok := errdefs.ReasonOf(nerr); ok || !errdefs.IsConflict(nerr) {
+		t.Fatalf("unexpected error %v with reason %+v", nerr, r)
+	}
+}
+
 func TestGRPCCustomDetails(t *testing.T) {
 	typeurl.Register(&TestError{}, t.Name())
 	expected := &TestError{
@@ -274,3 +660,110 @@ func TestGRPCNestedError(t *testing.T) {
 
 	checkError(ToNative(ToGRPC(werr)))
 }
+
+func TestGRPCWrappedStatus(t *testing.T) {
+	inner := ToGRPC(errdefs.WithReason(fmt.Errorf("blob sha256:abc: %w", errdefs.ErrConflict), "containerd.io", "BLOB_LOCKED", nil))
+
+	err := fmt.Errorf("pulling layer %s: %w", "sha256:abc", inner)
+	gerr := ToGRPC(err)
+	if _, ok := gerr.(interface{ GRPCStatus() *status.Status }); !ok {
+		t.Fatalf("expected status error, got %T", gerr)
+	}
+	st, _ := status.FromError(gerr)
+	if st.Code() != codes.FailedPrecondition || st.Message() != "pulling layer sha256:abc: blob sha256:abc: conflict" {
+		t.Fatalf("unexpected status %v", st)
+	}
+	nerr := ToNative(gerr)
+	if !errdefs.IsConflict(nerr) || nerr.Error() != st.Message() {
+		t.Fatalf("unexpected error %v", nerr)
+	}
+	if r, _ := errdefs.ReasonOf(nerr); r.Reason != "BLOB_LOCKED" {
+		t.Fatalf("original details should be kept, got reason %+v", r)
+	}
+
+	// Local details around the status
+	err = errdefs.WithRetryAfter(errors.Join(
+		fmt.Errorf("cleanup: %w", errdefs.ErrPermissionDenied),
+		err,
+	), time.Second)
+	gerr = ToGRPC(err)
+	st, _ = status.FromError(gerr)
+	if st.Code() != codes.FailedPrecondition || st.Message() != "cleanup: permission denied\npulling layer sha256:abc: blob sha256:abc: conflict" {
+		t.Fatalf("unexpected status %v", st)
+	}
+	nerr = ToNative(gerr)
+	if !errdefs.IsConflict(nerr) || !errdefs.IsPermissionDenied(nerr) {
+		t.Fatalf("expected conflict and permission denied, got %v", nerr)
+	}
+	if d, ok := errdefs.RetryAfter(nerr); !ok || d != time.Second {
+		t.Fatalf("unexpected retry delay %v", d)
+	}
+	if r, _ := errdefs.ReasonOf(nerr); r.Reason != "BLOB_LOCKED" {
+		t.Fatalf("original details should be kept, got reason %+v", r)
+	}
+
+	// Unwrapped status errors are returned unchanged
+	if gerr := ToGRPC(inner); gerr != inner {
+		t.Fatalf("expected unchanged status error, got %v", gerr)
+	}
+}
+
+func TestGRPCNativeStatus(t *testing.T) {
+	st, _ := status.New(codes.FailedPrecondition, "snapshot busy: conflict").WithDetails(
+		&errdetails.ErrorInfo{Domain: ClassDomain, Reason: "CONFLICT"},
+		&errdetails.DebugInfo{Detail: "held by lease pull-1"},
+	)
+	orig := st.Proto()
+	orig.Details = append(orig.Details, &anypb.Any{TypeUrl: "example.com/unknown.Detail", Value: []byte{0x0a, 0x01, 0x78}})
+	gerr := status.ErrorProto(orig)
+
+	nerr := ToNative(gerr)
+	if !errdefs.IsConflict(nerr) || !strings.HasPrefix(nerr.Error(), "snapshot busy: conflict\n") {
+		t.Fatalf("unexpected error %v", nerr)
+	}
+	gs, ok := nerr.(interface{ GRPCStatus() *status.Status })
+	if !ok || !proto.Equal(gs.GRPCStatus().Proto(), orig) {
+		t.Fatal("expected original status from decoded error")
+	}
+	if ToNative(nerr) != nerr {
+		t.Fatal("decoded error should be returned unchanged")
+	}
+
+	st, _ = status.FromError(ToGRPC(nerr))
+	if !proto.Equal(st.Proto(), orig) {
+		t.Fatalf("expected original status, got %v", st.Proto())
+	}
+
+	// Wrapped decoded errors keep the original details
+	st, _ = status.FromError(ToGRPC(fmt.Errorf("remove snapshot: %w", nerr)))
+	if st.Code() != codes.FailedPrecondition || st.Message() != "remove snapshot: snapshot busy: conflict" {
+		t.Fatalf("unexpected status %v", st)
+	}
+	if details := st.Proto().GetDetails(); len(details) < len(orig.Details) || !proto.Equal(details[len(orig.Details)-1], orig.Details[len(orig.Details)-1]) {
+		t.Fatalf("original details not kept: %v", details)
+	}
+}
+
+func TestGRPCWrappedNative(t *testing.T) {
+	// Decoded errors with reasons and typed errors are not comparable
+	reason := ToNative(ToGRPC(errdefs.WithReason(errdefs.ErrNotFound, "containerd.io", "IMAGE_MISSING", nil)))
+	gerr := ToGRPC(fmt.Errorf("pulling: %w", reason))
+	st, _ := status.FromError(gerr)
+	if st.Code() != codes.NotFound || st.Message() != "pulling: not found" {
+		t.Fatalf("unexpected status %v", st)
+	}
+	if r, _ := errdefs.ReasonOf(ToNative(gerr)); r.Reason != "IMAGE_MISSING" {
+		t.Fatalf("unexpected reason %+v", r)
+	}
+
+	fields := ToNative(ToGRPC(errdefs.InvalidFields{{Field: "ref", Description: "empty", Reason: "REQUIRED"}}))
+	gerr = ToGRPC(errors.Join(fields, fmt.Errorf("cleanup: %w", errdefs.ErrUnavailable)))
+	st, _ = status.FromError(gerr)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("unexpected status %v", st)
+	}
+	nerr := ToNative(gerr)
+	if v := errdefs.FieldViolations(nerr); len(v) != 1 || v[0].Reason != "REQUIRED" || !errdefs.IsUnavailable(nerr) {
+		t.Fatalf("unexpected error %v with field violations %v", nerr, v)
+	}
+}
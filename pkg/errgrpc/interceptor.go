@@ -0,0 +1,121 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/stack"
+)
+
+// UnaryServerInterceptor returns a server interceptor which converts the
+// errors returned by handlers using an Encoder configured with the options,
+// see Encoder.ToGRPC. A panic in the handler is returned as an internal
+// error with the stack trace of the panic, which is only sent to callers
+// receiving debug details, see WithDebugPeers.
+func UnaryServerInterceptor(opts ...EncoderOpt) grpc.UnaryServerInterceptor {
+	e := NewEncoder(opts...)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				resp, err = nil, panicError(v)
+			}
+			err = e.ToGRPC(ctx, err)
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a server interceptor which converts the
+// errors returned by stream handlers in the same way as
+// UnaryServerInterceptor.
+func StreamServerInterceptor(opts ...EncoderOpt) grpc.StreamServerInterceptor {
+	e := NewEncoder(opts...)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = panicError(v)
+			}
+			err = e.ToGRPC(ss.Context(), err)
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// panicError returns an internal error for the recovered panic value. The
+// stack trace is taken while the panicking frames are still on the stack.
+func panicError(v any) error {
+	return stack.Join(fmt.Errorf("panic: %v: %w", v, errdefs.ErrInternal))
+}
+
+// UnaryClientInterceptor returns a client interceptor which converts the
+// errors returned by calls using ToNative.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return ToNative(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamClientInterceptor returns a client interceptor which converts the
+// errors returned when creating streams and from the methods of the streams,
+// such as RecvMsg and SendMsg, using ToNative. The io.EOF error marking the
+// end of a stream is returned unchanged.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, ToNative(err)
+		}
+		return &clientStream{ClientStream: cs}, nil
+	}
+}
+
+// clientStream converts the errors returned by a client stream
+type clientStream struct {
+	grpc.ClientStream
+}
+
+func (s *clientStream) Header() (md metadata.MD, err error) {
+	md, err = s.ClientStream.Header()
+	return md, streamError(err)
+}
+
+func (s *clientStream) CloseSend() error {
+	return streamError(s.ClientStream.CloseSend())
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	return streamError(s.ClientStream.SendMsg(m))
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	return streamError(s.ClientStream.RecvMsg(m))
+}
+
+func streamError(err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+	return ToNative(err)
+}
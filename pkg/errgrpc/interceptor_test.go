@@ -0,0 +1,165 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/redact"
+	"github.com/containerd/errdefs/pkg/stack"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	intercept := UnaryServerInterceptor(WithRedactor(redact.Default()), WithDebugPeers(DebugRequested))
+	info := &grpc.UnaryServerInfo{FullMethod: "/containerd.services.images.v1.Images/Get"}
+
+	_, err := intercept(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, fmt.Errorf("image %q with token=s3cr3t: %w", "redis", errdefs.ErrNotFound)
+	})
+	st, _ := status.FromError(err)
+	if st.Code() != codes.NotFound || st.Message() != `image "redis" with token=`+redact.Placeholder+": not found" {
+		t.Fatalf("unexpected status %v", st)
+	}
+
+	resp, err := intercept(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	if resp != "ok" || err != nil {
+		t.Fatalf("unexpected response %v, %v", resp, err)
+	}
+
+	for _, debug := range []bool{false, true} {
+		ctx := context.Background()
+		if debug {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(DebugMetadataKey, "1"))
+		}
+		_, err = intercept(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+			panic("nil map")
+		})
+		nerr := ToNative(err)
+		if !errdefs.IsInternal(nerr) || !strings.HasPrefix(nerr.Error(), "panic: nil map") {
+			t.Fatalf("unexpected panic error %v", nerr)
+		}
+		var trace interface{ StackTrace() stack.Trace }
+		if errors.As(nerr, &trace) != debug {
+			t.Fatalf("expected stack trace sent %t", debug)
+		}
+		if debug && !strings.Contains(fmt.Sprintf("%+v", nerr), "TestUnaryServerInterceptor") {
+			t.Fatalf("stack trace should include the panicking frame: %+v", nerr)
+		}
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	intercept := StreamServerInterceptor()
+	ss := fakeServerStream{ctx: context.Background()}
+	info := &grpc.StreamServerInfo{FullMethod: "/containerd.services.events.v1.Events/Subscribe"}
+
+	err := intercept(nil, ss, info, func(srv any, stream grpc.ServerStream) error {
+		return fmt.Errorf("subscribe: %w", errdefs.ErrUnavailable)
+	})
+	if st, _ := status.FromError(err); st.Code() != codes.Unavailable {
+		t.Fatalf("unexpected status %v", st)
+	}
+
+	err = intercept(nil, ss, info, func(srv any, stream grpc.ServerStream) error {
+		panic("closed channel")
+	})
+	if nerr := ToNative(err); !errdefs.IsInternal(nerr) {
+		t.Fatalf("unexpected panic error %v", nerr)
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	intercept := UnaryClientInterceptor()
+	err := intercept(context.Background(), "/test", nil, nil, nil, func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return ToGRPC(fmt.Errorf("lease abc: %w", errdefs.ErrConflict))
+	})
+	if !errdefs.IsConflict(err) || err.Error() != "lease abc: conflict" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	err = intercept(context.Background(), "/test", nil, nil, nil, func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+type fakeClientStream struct {
+	grpc.ClientStream
+	recv []error
+}
+
+func (s *fakeClientStream) SendMsg(m any) error {
+	return ToGRPC(fmt.Errorf("send: %w", errdefs.ErrResourceExhausted))
+}
+
+func (s *fakeClientStream) RecvMsg(m any) error {
+	err := s.recv[0]
+	s.recv = s.recv[1:]
+	return err
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	intercept := StreamClientInterceptor()
+	_, err := intercept(context.Background(), &grpc.StreamDesc{}, nil, "/test", func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return nil, ToGRPC(fmt.Errorf("dial: %w", errdefs.ErrUnavailable))
+	})
+	if !errdefs.IsUnavailable(err) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	cs, err := intercept(context.Background(), &grpc.StreamDesc{}, nil, "/test", func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{recv: []error{nil, io.EOF, ToGRPC(errdefs.ErrNotFound)}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.SendMsg(nil); !errdefs.IsResourceExhausted(err) {
+		t.Fatalf("unexpected send error %v", err)
+	}
+	if err := cs.RecvMsg(nil); err != nil {
+		t.Fatalf("unexpected receive error %v", err)
+	}
+	if err := cs.RecvMsg(nil); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if err := cs.RecvMsg(nil); !errdefs.IsNotFound(err) {
+		t.Fatalf("unexpected receive error %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errgrpc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/containerd/typeurl/v2"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/stack"
+)
+
+// DefaultMaxStatusSize is the default limit, in bytes, of the encoded status
+// returned by ToGRPC, including the message and details. The status is sent
+// base64 encoded in the trailers of the response, which peers commonly limit
+// to between 8 and 16 KiB in total, this limit is 8 KiB once encoded.
+const DefaultMaxStatusSize = 6 << 10
+
+// limitStatus drops details from the status until its encoded size fits
+// within max bytes, returning the status unchanged if it already fits. Stack
+// traces are dropped first, including those in nested statuses, followed by
+// the nested statuses and then any other details, starting from the last
+// detail. The code, message and error info are always kept, even if the
+// status still does not fit. When any detail is dropped, the number of
+// dropped details is added to the metadata of the error info, which is
+// added if the status has none.
+func limitStatus(st *spb.Status, max int) *spb.Status {
+	if proto.Size(st) <= max {
+		return st
+	}
+	st = proto.Clone(st).(*spb.Status)
+	max -= markerSize
+	dropped := 0
+
+	// The error info is kept
+	var info *errdetails.ErrorInfo
+	keep := 0
+	if len(st.Details) > 0 {
+		info = &errdetails.ErrorInfo{}
+		if st.Details[0].UnmarshalTo(info) == nil {
+			keep = 1
+		} else {
+			info = nil
+		}
+	}
+
+	// Stack traces, including those in nested statuses
+	for i := len(st.Details) - 1; i >= keep && proto.Size(st) > max; i-- {
+		if isStackDetail(st.Details[i]) {
+			st.Details = append(st.Details[:i], st.Details[i+1:]...)
+			dropped++
+		} else if nested, n := stripStacks(st.Details[i]); n > 0 {
+			st.Details[i] = nested
+			dropped += n
+		}
+	}
+
+	// Nested statuses
+	for i := len(st.Details) - 1; i >= keep && proto.Size(st) > max; i-- {
+		if st.Details[i].MessageIs((*spb.Status)(nil)) {
+			st.Details = append(st.Details[:i], st.Details[i+1:]...)
+			dropped++
+		}
+	}
+
+	// Everything but the error info
+	for i := len(st.Details) - 1; i >= keep && proto.Size(st) > max; i-- {
+		st.Details = st.Details[:i]
+		dropped++
+	}
+
+	if dropped > 0 {
+		if info == nil {
+			info = &errdetails.ErrorInfo{
+				Domain: ClassDomain,
+				Reason: codeClass(uint32(st.Code)).String(),
+			}
+			st.Details = append([]*anypb.Any{nil}, st.Details...)
+		}
+		if info.Metadata == nil {
+			info.Metadata = map[string]string{}
+		}
+		info.Metadata[droppedKey] = strconv.Itoa(dropped)
+		if a, err := anypb.New(info); err == nil {
+			st.Details[0] = a
+		} else {
+			st.Details = st.Details[1:]
+		}
+	}
+	return st
+}
+
+// markerSize is the space reserved for recording the truncation, which is
+// at most an error info carrying the class and the number of dropped details
+var markerSize = func() int {
+	var reason string
+	for _, info := range errdefs.Classes() {
+		if len(info.Class.String()) > len(reason) {
+			reason = info.Class.String()
+		}
+	}
+	marker, _ := anypb.New(&errdetails.ErrorInfo{
+		Domain:   ClassDomain,
+		Reason:   reason,
+		Metadata: map[string]string{droppedKey: strconv.Itoa(1 << 20)},
+	})
+	return proto.Size(&spb.Status{Details: []*anypb.Any{marker}})
+}()
+
+// isStackDetail returns true if the detail is a stack trace from the stack
+// package
+func isStackDetail(a *anypb.Any) bool {
+	// Details from toProtoMessage are sent as an Any within the Any
+	var inner anypb.Any
+	if a.MessageIs(&inner) && a.UnmarshalTo(&inner) == nil {
+		a = &inner
+	}
+	v, err := typeurl.UnmarshalAny(a)
+	if err != nil {
+		return false
+	}
+	_, ok := v.(interface{ StackTrace() stack.Trace })
+	return ok
+}
+
+// stripStacks returns the detail with the stack traces removed if it is a
+// nested status, along with the number of stack traces removed.
+func stripStacks(a *anypb.Any) (*anypb.Any, int) {
+	var nested spb.Status
+	if !a.MessageIs(&nested) || a.UnmarshalTo(&nested) != nil {
+		return a, 0
+	}
+	n := 0
+	details := nested.Details[:0]
+	for _, d := range nested.Details {
+		if isStackDetail(d) {
+			n++
+			continue
+		}
+		d, dn := stripStacks(d)
+		n += dn
+		details = append(details, d)
+	}
+	if n == 0 {
+		return a, 0
+	}
+	nested.Details = details
+	stripped, err := anypb.New(&nested)
+	if err != nil {
+		return a, 0
+	}
+	return stripped, n
+}
+
+// truncatedError records that details were dropped from the status the
+// error was decoded from
+type truncatedError struct {
+	dropped int
+}
+
+func (e truncatedError) Error() string {
+	return fmt.Sprintf("%d error details truncated", e.dropped)
+}
+
+func (truncatedError) CollapseError() {}
+
+// DetailsTruncated returns the number of details which were dropped from
+// the gRPC status the error was decoded from by ToNative, to fit the status
+// within the size limit of the sender, see WithMaxStatusSize. The error
+// still has the code and message of the status, but may be missing
+// wrapped and joined errors or stack traces.
+func DetailsTruncated(err error) (dropped int, truncated bool) {
+	var te truncatedError
+	if errors.As(err, &te) {
+		return te.dropped, true
+	}
+	return 0, false
+}
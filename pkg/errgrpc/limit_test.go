@@ -0,0 +1,110 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/stack"
+)
+
+func TestMaxStatusSize(t *testing.T) {
+	var layers []error
+	for i := 0; i < 20; i++ {
+		layers = append(layers, stack.Join(fmt.Errorf("layer %d: %w", i, errdefs.ErrUnavailable)))
+	}
+	err := errdefs.WithRetryAfter(fmt.Errorf("pull: %w", errors.Join(layers...)), time.Second)
+
+	unlimited, _ := status.FromError(NewEncoder(WithMaxStatusSize(-1)).ToGRPC(context.Background(), err))
+	if size := proto.Size(unlimited.Proto()); size <= 2048 {
+		t.Fatalf("expected large status, got %d bytes", size)
+	}
+	if _, truncated := DetailsTruncated(ToNative(unlimited.Err())); truncated {
+		t.Fatal("unlimited status should not be truncated")
+	}
+
+	for _, max := range []int{4096, 2048, 1024} {
+		gerr := NewEncoder(WithMaxStatusSize(max)).ToGRPC(context.Background(), err)
+		st, _ := status.FromError(gerr)
+		if size := proto.Size(st.Proto()); size > max {
+			t.Fatalf("%d: status of %d bytes exceeds limit", max, size)
+		}
+		if st.Code() != codes.Unavailable || st.Message() != err.Error() {
+			t.Fatalf("%d: unexpected status %v", max, st)
+		}
+		nerr := ToNative(gerr)
+		if !errdefs.IsUnavailable(nerr) {
+			t.Fatalf("%d: expected unavailable, got %v", max, nerr)
+		}
+		if dropped, truncated := DetailsTruncated(nerr); !truncated || dropped == 0 {
+			t.Fatalf("%d: expected truncation to be recorded", max)
+		}
+	}
+
+	// The stack traces go first
+	gerr := NewEncoder(WithMaxStatusSize(proto.Size(unlimited.Proto())-1)).ToGRPC(context.Background(), err)
+	nerr := ToNative(gerr)
+	if dropped, _ := DetailsTruncated(nerr); dropped != 1 {
+		t.Fatalf("expected a single stack trace dropped, got %d", dropped)
+	}
+	if _, ok := errdefs.RetryAfter(nerr); !ok {
+		t.Fatal("retry delay should be kept")
+	}
+	stacks, nested := countDetails(unlimited.Proto())
+	st, _ := status.FromError(gerr)
+	if s, n := countDetails(st.Proto()); s != stacks-1 || n != nested {
+		t.Fatalf("expected one of %d stack traces dropped and %d nested statuses kept, got %d and %d", stacks, nested, s, n)
+	}
+}
+
+// countDetails returns the number of stack traces and nested statuses in
+// the details of the status
+func countDetails(st *spb.Status) (stacks, nested int) {
+	for _, d := range st.GetDetails() {
+		if isStackDetail(d) {
+			stacks++
+		} else if d.MessageIs((*spb.Status)(nil)) {
+			nested++
+		}
+	}
+	return stacks, nested
+}
+
+func TestMaxStatusSizeMessage(t *testing.T) {
+	err := fmt.Errorf("%s: %w", strings.Repeat("x", 4096), errdefs.ErrConflict)
+	st, _ := status.FromError(NewEncoder(WithMaxStatusSize(1024)).ToGRPC(context.Background(), err))
+	if st.Code() != codes.FailedPrecondition || st.Message() != err.Error() {
+		t.Fatalf("code and message should be kept, got %v", st.Code())
+	}
+	if nerr := ToNative(st.Err()); !errdefs.IsConflict(nerr) {
+		t.Fatalf("class should be kept, got %v", nerr)
+	}
+	if dropped, _ := DetailsTruncated(ToNative(st.Err())); dropped != 0 {
+		t.Fatalf("unexpected dropped details %d", dropped)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errhttp
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+)
+
+// maxErrorBody is the maximum number of bytes read from the body of an
+// unsuccessful response
+const maxErrorBody = 64 << 10
+
+// FromResponse returns the error for an unsuccessful response, including the
+// message sent by the server. A nil error is returned for successful
+// responses.
+//
+// The body is read, up to a limit, but not closed. Plain text, JSON and
+// problem details bodies are understood, other bodies are ignored. When the
+// response has a Retry-After header, the delay is returned by
+// errdefs.RetryAfter on the error.
+func FromResponse(resp *http.Response) error {
+	err := ParseResponse(resp)
+	if err == nil {
+		return nil
+	}
+
+	if resp.Body != nil {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		switch {
+		case mt == ProblemContentType, mt == "application/json", strings.HasSuffix(mt, "+json"):
+			var body struct {
+				Problem
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(b, &body) == nil {
+				if body.Status == 0 {
+					body.Status = resp.StatusCode
+				}
+				if body.Class == "" {
+					body.Class = resp.Header.Get(ClassHeader)
+				}
+				if body.Detail == "" {
+					body.Detail = body.Message
+				}
+				err = body.ToNative()
+			}
+		case mt == "text/plain", mt == "" && len(b) > 0:
+			err = withDescription(err, strings.TrimSpace(string(b)))
+		}
+	}
+
+	if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		err = errdefs.WithRetryAfter(err, d)
+	}
+
+	return err
+}
+
+// parseRetryAfter parses the value of a Retry-After header as either a
+// number of seconds or an HTTP date
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// NewTransport returns a round tripper which returns the error from
+// FromResponse for unsuccessful responses, allowing HTTP clients to check
+// errors in the same way as other errdefs errors. Redirect responses are
+// returned to be followed by the client. If rt is nil, the
+// http.DefaultTransport is used.
+func NewTransport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &transport{rt: rt}
+}
+
+type transport struct {
+	rt http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return resp, nil
+	}
+	if err := FromResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,217 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errhttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containerd/errdefs"
+)
+
+func TestFromResponse(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		cause      error
+		str        string
+		retryAfter time.Duration
+		hasRetry   bool
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			body:   "ok",
+		},
+		{
+			name:   "plain text",
+			status: http.StatusNotFound,
+			header: http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+			body:   "no such image\n",
+			cause:  errdefs.ErrNotFound,
+			str:    "no such image",
+		},
+		{
+			name:   "plain text with class",
+			status: http.StatusConflict,
+			header: http.Header{"Content-Type": {"text/plain"}, ClassHeader: {"ALREADY_EXISTS"}},
+			body:   "lease abc: already exists\n",
+			cause:  errdefs.ErrAlreadyExists,
+			str:    "lease abc: already exists",
+		},
+		{
+			name:   "problem",
+			status: http.StatusConflict,
+			header: http.Header{"Content-Type": {ProblemContentType}},
+			body:   `{"type":"urn:containerd:errdefs:ABORTED","status":409,"detail":"retry transaction"}`,
+			cause:  errdefs.ErrAborted,
+			str:    "retry transaction",
+		},
+		{
+			name:   "json message",
+			status: http.StatusBadRequest,
+			header: http.Header{"Content-Type": {"application/json"}},
+			body:   `{"message":"invalid reference format"}`,
+			cause:  errdefs.ErrInvalidArgument,
+			str:    "invalid reference format",
+		},
+		{
+			name:   "invalid json",
+			status: http.StatusBadRequest,
+			header: http.Header{"Content-Type": {"application/json"}},
+			body:   `invalid`,
+			cause:  errdefs.ErrInvalidArgument,
+		},
+		{
+			name:   "html ignored",
+			status: http.StatusBadGateway,
+			header: http.Header{"Content-Type": {"text/html"}},
+			body:   "<html>bad gateway</html>",
+			cause:  ToNative(http.StatusBadGateway),
+		},
+		{
+			name:       "retry after seconds",
+			status:     http.StatusTooManyRequests,
+			header:     http.Header{"Retry-After": {"30"}},
+			cause:      errdefs.ErrResourceExhausted,
+			retryAfter: 30 * time.Second,
+			hasRetry:   true,
+		},
+		{
+			name:     "retry after date",
+			status:   http.StatusServiceUnavailable,
+			header:   http.Header{"Retry-After": {time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)}},
+			cause:    errdefs.ErrUnavailable,
+			hasRetry: true,
+		},
+		{
+			name:   "retry after invalid",
+			status: http.StatusServiceUnavailable,
+			header: http.Header{"Retry-After": {"soon"}},
+			cause:  errdefs.ErrUnavailable,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			header := tc.header
+			if header == nil {
+				header = http.Header{}
+			}
+			resp := &http.Response{
+				StatusCode: tc.status,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(tc.body)),
+			}
+			err := FromResponse(resp)
+			if tc.cause == nil {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.cause) {
+				t.Fatalf("unexpected cause: !errors.Is(%v, %v)", err, tc.cause)
+			}
+			expected := tc.str
+			if expected == "" {
+				expected = tc.cause.Error()
+			}
+			if err.Error() != expected {
+				t.Fatalf("unexpected string: %q != %q", err.Error(), expected)
+			}
+
+			d, ok := errdefs.RetryAfter(err)
+			if ok != tc.hasRetry {
+				t.Fatalf("unexpected retry after presence on %v", err)
+			}
+			if d != tc.retryAfter {
+				t.Fatalf("unexpected retry after %v", d)
+			}
+		})
+	}
+}
+
+func TestFromResponseLimit(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Header:     http.Header{"Content-Type": {"text/plain"}},
+		Body:       io.NopCloser(strings.NewReader(strings.Repeat("x", 2*maxErrorBody))),
+	}
+	err := FromResponse(resp)
+	if !errdefs.IsInternal(err) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if l := len(err.Error()); l > maxErrorBody+len(": internal") {
+		t.Fatalf("message not limited, got %d bytes", l)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(func(w http.ResponseWriter, r *http.Request) error {
+		switch r.URL.Path {
+		case "/ok":
+			fmt.Fprint(w, "ok")
+			return nil
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+			return nil
+		case "/exists":
+			return fmt.Errorf("container %q: %w", "redis", errdefs.ErrAlreadyExists)
+		case "/busy":
+			return errdefs.ErrResourceExhausted.WithMessage("too many pulls")
+		}
+		return errdefs.ErrNotFound
+	}, WithRetryAfter(2*time.Second)))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	for _, path := range []string{"/ok", "/redirect"} {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(b) != "ok" {
+			t.Fatalf("%s: unexpected body %q", path, b)
+		}
+	}
+
+	_, err := client.Get(srv.URL + "/exists")
+	if !errdefs.IsAlreadyExists(err) || errdefs.IsConflict(err) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !strings.HasSuffix(err.Error(), `container "redis": already exists`) {
+		t.Fatalf("server message not preserved: %v", err)
+	}
+
+	_, err = client.Get(srv.URL + "/busy")
+	if !errdefs.IsResourceExhausted(err) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if d, ok := errdefs.RetryAfter(err); !ok || d != 2*time.Second {
+		t.Fatalf("retry after not preserved: %v", err)
+	}
+}
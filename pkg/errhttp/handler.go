@@ -0,0 +1,266 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errhttp
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/internal/cause"
+	"github.com/containerd/errdefs/pkg/redact"
+	"github.com/containerd/errdefs/pkg/stack"
+)
+
+// Handler is an HTTP handler which returns an error. When the handler returns
+// an error before writing a response, the error is written as the response.
+//
+// The response format is negotiated using the Accept header of the request,
+// choosing between problem details, JSON and plain text. The messages of
+// errors with a 5xx status code are replaced with the description of their
+// class, see NewHandler to change this behavior.
+type Handler func(http.ResponseWriter, *http.Request) error
+
+// ServeHTTP calls the handler and writes any returned error to the response.
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, h, &handlerOptions{})
+}
+
+type handlerOptions struct {
+	serverMessages bool
+	retryAfter     time.Duration
+	log            func(*http.Request, error)
+	trusted        func(*http.Request) bool
+	redactor       *redact.Redactor
+}
+
+// HandlerOpt is used to configure how errors are written by a handler
+type HandlerOpt func(*handlerOptions)
+
+// WithServerErrorMessages includes the messages of errors with a 5xx status
+// code in the response rather than the description of their class.
+func WithServerErrorMessages() HandlerOpt {
+	return func(o *handlerOptions) {
+		o.serverMessages = true
+	}
+}
+
+// WithTrustedRequests sets the function deciding whether the client making
+// a request is trusted. Trusted clients receive the internal messages of
+// errors, including errors with a 5xx status code, rather than their public
+// messages, see errdefs.WithPublicMessage.
+func WithTrustedRequests(fn func(*http.Request) bool) HandlerOpt {
+	return func(o *handlerOptions) {
+		o.trusted = fn
+	}
+}
+
+// WithRedactor sets the redactor applied to the messages written to the
+// response, including the details and descriptions of problems.
+func WithRedactor(r *redact.Redactor) HandlerOpt {
+	return func(o *handlerOptions) {
+		o.redactor = r
+	}
+}
+
+// WithRetryAfter sets the Retry-After header to the given delay on responses
+// for resource exhausted and unavailable errors. A delay carried by the
+// error, see errdefs.WithRetryAfter, is used instead when present.
+func WithRetryAfter(d time.Duration) HandlerOpt {
+	return func(o *handlerOptions) {
+		o.retryAfter = d
+	}
+}
+
+// WithErrorLogger calls the given function for every error written to a
+// response. The original error is provided before any message is removed.
+func WithErrorLogger(fn func(*http.Request, error)) HandlerOpt {
+	return func(o *handlerOptions) {
+		o.log = fn
+	}
+}
+
+// NewHandler returns an HTTP handler which calls the handler and writes any
+// returned error to the response using the provided options.
+func NewHandler(h Handler, opts ...HandlerOpt) http.Handler {
+	o := &handlerOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &handler{h: h, o: o}
+}
+
+// Middleware returns a function which wraps an HTTP handler, writing any
+// panic from the handler to the response as an internal error.
+func Middleware(opts ...HandlerOpt) func(http.Handler) http.Handler {
+	o := &handlerOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(next http.Handler) http.Handler {
+		return &handler{
+			h: func(w http.ResponseWriter, r *http.Request) error {
+				next.ServeHTTP(w, r)
+				return nil
+			},
+			o: o,
+		}
+	}
+}
+
+type handler struct {
+	h Handler
+	o *handlerOptions
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, h.h, h.o)
+}
+
+func serve(w http.ResponseWriter, r *http.Request, h Handler, o *handlerOptions) {
+	rw := &responseWriter{ResponseWriter: w}
+	defer func() {
+		if v := recover(); v != nil {
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			writeResponse(rw, r, stack.Join(fmt.Errorf("panic: %v: %w", v, errdefs.ErrInternal)), o)
+		}
+	}()
+	if err := h(rw, r); err != nil {
+		writeResponse(rw, r, err, o)
+	}
+}
+
+func writeResponse(w *responseWriter, r *http.Request, err error, o *handlerOptions) {
+	if o.log != nil {
+		o.log(r, err)
+	}
+	if w.wroteHeader {
+		// Response already started, nothing more can be sent
+		return
+	}
+
+	delay, retry := errdefs.RetryAfter(err)
+	if !retry && o.retryAfter > 0 && (errdefs.IsResourceExhausted(err) || errdefs.IsUnavailable(err)) {
+		delay, retry = o.retryAfter, true
+	}
+	trusted := o.trusted != nil && o.trusted(r)
+	if _, public := errdefs.PublicMessage(err); !public && !trusted && !o.serverMessages {
+		err = sanitize(err)
+	}
+	if retry {
+		err = errdefs.WithRetryAfter(err, delay)
+	}
+
+	switch mt := negotiate(r.Header.Values("Accept")); mt {
+	case ProblemContentType, "application/json":
+		writeProblem(w, r, err, mt, trusted, o.redactor)
+	default:
+		writeText(w, err, trusted, o.redactor)
+	}
+}
+
+// sanitize replaces errors with a 5xx status code with the error for their
+// class, dropping the message.
+func sanitize(err error) error {
+	if ToHTTP(err) < http.StatusInternalServerError {
+		return err
+	}
+	var unexpected cause.ErrUnexpectedStatus
+	if errors.As(err, &unexpected) && errdefs.IsUnknown(err) {
+		return unexpected
+	}
+	return errdefs.ClassOf(err).Err()
+}
+
+// negotiate returns the response media type with the highest preference in
+// the Accept header values, defaulting to problem details.
+func negotiate(accept []string) string {
+	if len(accept) == 0 {
+		return ProblemContentType
+	}
+	var (
+		best    string
+		bestQ   float64
+		offered = []string{ProblemContentType, "application/json", "text/plain"}
+	)
+	for _, value := range accept {
+		for _, mr := range strings.Split(value, ",") {
+			mt, params, err := mime.ParseMediaType(mr)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if qv, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(qv, 64); err != nil {
+					continue
+				}
+			}
+			for _, o := range offered {
+				if q > bestQ && mediaMatch(mt, o) {
+					best, bestQ = o, q
+					break
+				}
+			}
+		}
+	}
+	if best == "" {
+		return "text/plain"
+	}
+	return best
+}
+
+func mediaMatch(pattern, mt string) bool {
+	if pattern == "*/*" || pattern == mt {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mt, prefix+"/")
+	}
+	return false
+}
+
+// responseWriter tracks whether the response has been started
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
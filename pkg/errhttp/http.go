@@ -19,56 +19,84 @@
 //
 // The functions ToHTTP and ToNative can be used to map server-side and
 // client-side errors to the correct types.
+//
+// To preserve the message and exact class of an error, WriteProblem can be
+// used to send the error as problem details (RFC 9457) which are mapped back
+// to an error using Problem.ToNative. Handler and Middleware write errors
+// returned by HTTP handlers in the format requested by the client.
 package errhttp
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/containerd/errdefs"
 	"github.com/containerd/errdefs/pkg/internal/cause"
+	"github.com/containerd/errdefs/pkg/redact"
 )
 
-// ToHTTP returns the best status code for the given error
+// ClassHeader is the HTTP header used to send the errdefs class of an error
+// alongside the status code, such as "Errdefs-Class: ALREADY_EXISTS".
+const ClassHeader = "Errdefs-Class"
+
+// StatusClientClosedRequest is the non-standard status code used to report
+// a request which was canceled by the client before a response was sent.
+const StatusClientClosedRequest = 499
+
+// ToHTTP returns the best status code for the given error, which is the
+// status code of the class returned by errdefs.ClassOf. Errors with several
+// classes are sent with the same class as the ClassHeader and errgrpc.
+//
+// The error classes map to the following status codes:
+//
+//	InvalidArgument    400 Bad Request
+//	Unauthenticated    401 Unauthorized
+//	PermissionDenied   403 Forbidden
+//	NotFound           404 Not Found
+//	AlreadyExists      409 Conflict
+//	Conflict           409 Conflict
+//	Aborted            409 Conflict
+//	FailedPrecondition 412 Precondition Failed
+//	OutOfRange         416 Range Not Satisfiable
+//	ResourceExhausted  429 Too Many Requests
+//	Canceled           499 Client Closed Request
+//	Internal           500 Internal Server Error
+//	DataLoss           500 Internal Server Error
+//	Unknown            500 Internal Server Error
+//	NotImplemented     501 Not Implemented
+//	Unavailable        503 Service Unavailable
+//	DeadlineExceeded   504 Gateway Timeout
+//	NotModified        304 Not Modified
+//
+// Unknown errors caused by an unexpected status from another HTTP service
+// keep that status.
 func ToHTTP(err error) int {
-	switch {
-	case errdefs.IsNotFound(err):
-		return http.StatusNotFound
-	case errdefs.IsInvalidArgument(err):
-		return http.StatusBadRequest
-	case errdefs.IsConflict(err):
-		return http.StatusConflict
-	case errdefs.IsNotModified(err):
-		return http.StatusNotModified
-	case errdefs.IsFailedPrecondition(err):
-		return http.StatusPreconditionFailed
-	case errdefs.IsUnauthorized(err):
-		return http.StatusUnauthorized
-	case errdefs.IsPermissionDenied(err):
-		return http.StatusForbidden
-	case errdefs.IsResourceExhausted(err):
-		return http.StatusTooManyRequests
-	case errdefs.IsInternal(err):
-		return http.StatusInternalServerError
-	case errdefs.IsNotImplemented(err):
-		return http.StatusNotImplemented
-	case errdefs.IsUnavailable(err):
-		return http.StatusServiceUnavailable
-	case errdefs.IsUnknown(err):
+	c := errdefs.ClassOf(err)
+	if c == errdefs.ClassUnknown {
 		var unexpected cause.ErrUnexpectedStatus
 		if errors.As(err, &unexpected) && unexpected.Status >= 200 && unexpected.Status < 600 {
 			return unexpected.Status
 		}
-		return http.StatusInternalServerError
-	default:
-		return http.StatusInternalServerError
 	}
+	return c.Info().HTTPStatus
 }
 
-// ToNative returns the error best matching the HTTP status code
+// ToNative returns the error best matching the HTTP status code.
+//
+// When several error classes share a status code, the most general class
+// is returned, use ToNativeClass to recover the exact class. In addition to
+// the status codes returned by ToHTTP, the following are recognized:
+//
+//	408 Request Timeout         DeadlineExceeded
+//	410 Gone                    NotFound
+//	507 Insufficient Storage    ResourceExhausted
 func ToNative(statusCode int) error {
 	switch statusCode {
-	case http.StatusNotFound:
+	case http.StatusNotFound, http.StatusGone:
 		return errdefs.ErrNotFound
 	case http.StatusBadRequest:
 		return errdefs.ErrInvalidArgument
@@ -82,15 +110,89 @@ func ToNative(statusCode int) error {
 		return errdefs.ErrPermissionDenied
 	case http.StatusNotModified:
 		return errdefs.ErrNotModified
-	case http.StatusTooManyRequests:
+	case http.StatusTooManyRequests, http.StatusInsufficientStorage:
 		return errdefs.ErrResourceExhausted
+	case http.StatusRequestedRangeNotSatisfiable:
+		return errdefs.ErrOutOfRange
 	case http.StatusInternalServerError:
 		return errdefs.ErrInternal
 	case http.StatusNotImplemented:
 		return errdefs.ErrNotImplemented
 	case http.StatusServiceUnavailable:
 		return errdefs.ErrUnavailable
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return context.DeadlineExceeded
+	case StatusClientClosedRequest:
+		return context.Canceled
 	default:
 		return cause.ErrUnexpectedStatus{Status: statusCode}
 	}
 }
+
+// ToNativeClass returns the error for the class when the class is sent with
+// the given status code by ToHTTP, otherwise it returns the same as ToNative.
+// This is used to distinguish classes which share a status code, such as
+// AlreadyExists and Aborted which are both sent as 409 Conflict, when the
+// class has been provided alongside the status code.
+func ToNativeClass(statusCode int, class errdefs.Class) error {
+	if cls := class.Err(); ToHTTP(cls) == statusCode {
+		return cls
+	}
+	return ToNative(statusCode)
+}
+
+// WriteError writes the error to the response with the status code returned
+// by ToHTTP and the class of the error in the ClassHeader. The error message,
+// or the public message of the error if it has one, is written as a plain
+// text body. A delay carried by the error, see errdefs.WithRetryAfter, is
+// sent in the Retry-After header.
+func WriteError(w http.ResponseWriter, err error) {
+	writeText(w, err, false, nil)
+}
+
+// writeText writes the error as a plain text body, using the internal
+// message of the error when trusted, with the redactor applied
+func writeText(w http.ResponseWriter, err error, trusted bool, rd *redact.Redactor) {
+	code := ToHTTP(err)
+	h := w.Header()
+	h.Set(ClassHeader, errdefs.ClassOf(err).String())
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	setRetryAfter(h, err)
+	w.WriteHeader(code)
+	if code != http.StatusNotModified {
+		fmt.Fprintln(w, rd.String(message(err, trusted)))
+	}
+}
+
+// message returns the message of the error to send to the client, which is
+// the public message of the error unless trusted
+func message(err error, trusted bool) string {
+	if !trusted {
+		if msg, ok := errdefs.PublicMessage(err); ok {
+			return msg
+		}
+	}
+	return err.Error()
+}
+
+// ParseResponse returns the error for an unsuccessful response using the
+// status code and, when present, the class from the ClassHeader. A nil error
+// is returned for successful responses. The response body is not read.
+func ParseResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if c, err := errdefs.ParseClass(resp.Header.Get(ClassHeader)); err == nil {
+		return ToNativeClass(resp.StatusCode, c)
+	}
+	return ToNative(resp.StatusCode)
+}
+
+// setRetryAfter sets the Retry-After header to the delay carried by the
+// error, in whole seconds rounded up
+func setRetryAfter(h http.Header, err error) {
+	if d, ok := errdefs.RetryAfter(err); ok {
+		h.Set("Retry-After", strconv.Itoa(int((max(d, 0)+time.Second-1)/time.Second)))
+	}
+}
@@ -17,11 +17,15 @@
 package errhttp
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/internal/cause"
 )
 
 func TestHTTPNilInput(t *testing.T) {
@@ -30,6 +34,14 @@ func TestHTTPNilInput(t *testing.T) {
 	}
 }
 
+func TestHTTPClassTable(t *testing.T) {
+	for _, info := range errdefs.Classes() {
+		if rc := ToHTTP(info.Err); rc != info.HTTPStatus {
+			t.Errorf("%s: ToHTTP returned %d, class table has %d", info.Name, rc, info.HTTPStatus)
+		}
+	}
+}
+
 func TestHTTPRoundTrip(t *testing.T) {
 	errShouldLeaveAlone := errors.New("unknown to package")
 
@@ -82,6 +94,18 @@ func TestHTTPRoundTrip(t *testing.T) {
 			input: errdefs.ErrUnavailable,
 			cause: errdefs.ErrUnavailable,
 		},
+		{
+			input: errdefs.ErrOutOfRange,
+			cause: errdefs.ErrOutOfRange,
+		},
+		{
+			input: context.DeadlineExceeded,
+			cause: context.DeadlineExceeded,
+		},
+		{
+			input: context.Canceled,
+			cause: context.Canceled,
+		},
 		{
 			input: errShouldLeaveAlone,
 			cause: errdefs.ErrInternal,
@@ -108,3 +132,106 @@ func TestHTTPRoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPClassRoundTrip(t *testing.T) {
+	for _, info := range errdefs.Classes() {
+		info := info
+		t.Run(info.Name, func(t *testing.T) {
+			input := fmt.Errorf("wrapped: %w", info.Err)
+			ferr := ToNativeClass(ToHTTP(input), info.Class)
+			if c := errdefs.ClassOf(ferr); c != info.Class {
+				t.Fatalf("class not preserved: %v != %v", c, info.Class)
+			}
+		})
+	}
+}
+
+func TestHTTPToNativeClassMismatch(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		class  errdefs.Class
+		cause  error
+	}{
+		{http.StatusConflict, errdefs.ClassNotFound, errdefs.ErrConflict},
+		{http.StatusRequestTimeout, errdefs.ClassDeadlineExceeded, context.DeadlineExceeded},
+		{http.StatusGone, errdefs.ClassUnknown, errdefs.ErrNotFound},
+		{http.StatusInsufficientStorage, errdefs.ClassInternal, errdefs.ErrResourceExhausted},
+		{http.StatusTeapot, errdefs.ClassUnknown, cause.ErrUnexpectedStatus{Status: http.StatusTeapot}},
+	} {
+		if err := ToNativeClass(tc.status, tc.class); !errors.Is(err, tc.cause) {
+			t.Errorf("ToNativeClass(%d, %v) = %v, expected %v", tc.status, tc.class, err, tc.cause)
+		}
+	}
+}
+
+func TestHTTPWriteError(t *testing.T) {
+	for _, info := range errdefs.Classes() {
+		info := info
+		t.Run(info.Name, func(t *testing.T) {
+			input := fmt.Errorf("wrapped: %w", info.Err)
+			rec := httptest.NewRecorder()
+			WriteError(rec, input)
+			resp := rec.Result()
+			if resp.StatusCode != info.HTTPStatus {
+				t.Fatalf("unexpected status %d, expected %d", resp.StatusCode, info.HTTPStatus)
+			}
+			if h := resp.Header.Get(ClassHeader); h != info.Name {
+				t.Fatalf("unexpected class header %q", h)
+			}
+			if resp.StatusCode != http.StatusNotModified {
+				if body := rec.Body.String(); body != input.Error()+"\n" {
+					t.Fatalf("unexpected body %q", body)
+				}
+			}
+
+			ferr := ParseResponse(resp)
+			if !errors.Is(ferr, info.Err) {
+				t.Fatalf("class not preserved: !errors.Is(%v, %v)", ferr, info.Err)
+			}
+			if c := errdefs.ClassOf(ferr); c != info.Class {
+				t.Fatalf("unexpected class %v", c)
+			}
+		})
+	}
+}
+
+func TestHTTPWriteErrorJoined(t *testing.T) {
+	input := errors.Join(fmt.Errorf("svc down: %w", errdefs.ErrUnavailable), errdefs.ErrNotFound)
+	rec := httptest.NewRecorder()
+	WriteError(rec, input)
+	resp := rec.Result()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get(ClassHeader) != "UNAVAILABLE" {
+		t.Fatalf("status %d and class header %q do not match the class of the error", resp.StatusCode, resp.Header.Get(ClassHeader))
+	}
+	if c := errdefs.ClassOf(ParseResponse(resp)); c != errdefs.ClassUnavailable {
+		t.Fatalf("unexpected class %v", c)
+	}
+}
+
+func TestHTTPParseResponse(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		class  string
+		cause  error
+	}{
+		{http.StatusOK, "NOT_FOUND", nil},
+		{http.StatusNoContent, "", nil},
+		{http.StatusConflict, "", errdefs.ErrConflict},
+		{http.StatusConflict, "ABORTED", errdefs.ErrAborted},
+		{http.StatusConflict, "not a class", errdefs.ErrConflict},
+		{http.StatusBadGateway, "INTERNAL", cause.ErrUnexpectedStatus{Status: http.StatusBadGateway}},
+	} {
+		resp := &http.Response{StatusCode: tc.status, Header: http.Header{}}
+		if tc.class != "" {
+			resp.Header.Set(ClassHeader, tc.class)
+		}
+		err := ParseResponse(resp)
+		if tc.cause == nil {
+			if err != nil {
+				t.Errorf("%d %q: unexpected error %v", tc.status, tc.class, err)
+			}
+		} else if !errors.Is(err, tc.cause) {
+			t.Errorf("%d %q: expected %v, got %v", tc.status, tc.class, tc.cause, err)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/internal/types"
+	"github.com/containerd/errdefs/pkg/redact"
+)
+
+const (
+	// ProblemContentType is the media type of problem details (RFC 9457)
+	ProblemContentType = "application/problem+json"
+
+	// ProblemTypePrefix is the prefix of the problem type URI, the problem
+	// type for each class is the prefix followed by the class name, such as
+	// "urn:containerd:errdefs:NOT_FOUND".
+	ProblemTypePrefix = "urn:containerd:errdefs:"
+)
+
+// Problem is the problem details (RFC 9457) representation of an error.
+//
+// In addition to the members defined by RFC 9457, the class of the error,
+// the errors joined to make up the error and the typed errors defined by
+// errdefs are included as extension members.
+type Problem struct {
+	// Type is the problem type URI for the class of the error
+	Type string `json:"type,omitempty"`
+
+	// Title is the description of the class of the error
+	Title string `json:"title,omitempty"`
+
+	// Status is the HTTP status code for the error
+	Status int `json:"status,omitempty"`
+
+	// Detail is the error message
+	Detail string `json:"detail,omitempty"`
+
+	// Instance identifies the request which the error occurred in
+	Instance string `json:"instance,omitempty"`
+
+	// Class is the canonical name of the class of the error
+	Class string `json:"class,omitempty"`
+
+	// Reason is the machine readable reason for the error, see
+	// errdefs.ReasonOf
+	Reason *Reason `json:"reason,omitempty"`
+
+	// Errors are the problems for each of the joined errors which
+	// make up the error
+	Errors []*Problem `json:"errors,omitempty"`
+
+	// InvalidParams are the invalid fields of the request, see
+	// errdefs.FieldViolations
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+
+	// Preconditions are the preconditions which were not met, see
+	// errdefs.PreconditionViolations
+	Preconditions []Precondition `json:"preconditions,omitempty"`
+
+	// Quotas are the quotas which were exceeded, see
+	// errdefs.QuotaViolations
+	Quotas []Quota `json:"quotas,omitempty"`
+
+	// Resources are the resources the error is for, see
+	// errdefs.ResourceErrors
+	Resources []Resource `json:"resources,omitempty"`
+}
+
+// Reason is a machine readable reason for an error, see errdefs.ReasonInfo.
+type Reason struct {
+	// Domain is the logical grouping the reason belongs to
+	Domain string `json:"domain,omitempty"`
+
+	// Reason is the identifier of the reason within the domain
+	Reason string `json:"reason"`
+
+	// Metadata is additional structured information about the error
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// InvalidParam is an invalid field of a request, using the "invalid-params"
+// extension member from the examples of RFC 9457.
+type InvalidParam struct {
+	// Name is the path to the field
+	Name string `json:"name"`
+
+	// Reason explains why the field is invalid
+	Reason string `json:"reason,omitempty"`
+
+	// Code is the identifier for the cause of the violation, such as
+	// "REQUIRED", see errdefs.FieldViolation.Reason
+	Code string `json:"code,omitempty"`
+}
+
+// Precondition is a precondition of an operation which was not met, see
+// errdefs.PreconditionViolation.
+type Precondition struct {
+	// Type identifies the kind of precondition which failed
+	Type string `json:"type,omitempty"`
+
+	// Subject identifies what failed the precondition
+	Subject string `json:"subject,omitempty"`
+
+	// Description explains how the precondition failed
+	Description string `json:"description,omitempty"`
+}
+
+// Quota is a quota which was exceeded, see errdefs.QuotaViolation.
+type Quota struct {
+	// Subject identifies what the quota applies to
+	Subject string `json:"subject,omitempty"`
+
+	// Limit is the name of the quota limit
+	Limit string `json:"limit,omitempty"`
+
+	// Current is the current usage counted against the limit
+	Current int64 `json:"current,omitempty"`
+
+	// Max is the value of the limit
+	Max int64 `json:"max,omitempty"`
+
+	// Description explains how the quota was exceeded
+	Description string `json:"description,omitempty"`
+}
+
+// Resource is a resource an error is for, see errdefs.ResourceError.
+type Resource struct {
+	// Type is the kind of resource
+	Type string `json:"type,omitempty"`
+
+	// Name identifies the resource
+	Name string `json:"name,omitempty"`
+
+	// Owner is the owner of the resource, such as its namespace
+	Owner string `json:"owner,omitempty"`
+
+	// Operation is the operation which failed on the resource
+	Operation string `json:"operation,omitempty"`
+
+	// Class is the canonical name of the class of the resource error
+	Class string `json:"class,omitempty"`
+}
+
+// NewProblem returns the problem details for the error. When the error has
+// a public message, see errdefs.WithPublicMessage, the public message is used
+// as the detail and the joined errors are left out. The reason and the typed
+// errors found anywhere in the error are added to the returned problem
+// rather than to the problems of the joined errors.
+func NewProblem(err error) *Problem {
+	return problemFor(err, false)
+}
+
+// problemFor returns the problem details for the error, using the internal
+// message of the error when trusted
+func problemFor(err error, trusted bool) *Problem {
+	var p *Problem
+	if msg, ok := errdefs.PublicMessage(err); ok && !trusted {
+		p = classProblem(err)
+		p.Detail = msg
+	} else {
+		p = newProblem(err)
+	}
+	if r, ok := errdefs.ReasonOf(err); ok {
+		p.Reason = &Reason{Domain: r.Domain, Reason: r.Reason, Metadata: r.Metadata}
+	}
+	for _, v := range errdefs.FieldViolations(err) {
+		p.InvalidParams = append(p.InvalidParams, InvalidParam{Name: v.Field, Reason: v.Description, Code: v.Reason})
+	}
+	for _, v := range errdefs.PreconditionViolations(err) {
+		p.Preconditions = append(p.Preconditions, Precondition(v))
+	}
+	for _, v := range errdefs.QuotaViolations(err) {
+		p.Quotas = append(p.Quotas, Quota(v))
+	}
+	for _, r := range errdefs.ResourceErrors(err) {
+		p.Resources = append(p.Resources, Resource{
+			Type:      r.Type,
+			Name:      r.Name,
+			Owner:     r.Owner,
+			Operation: r.Operation,
+			Class:     errdefs.ClassOf(r).String(),
+		})
+	}
+	return p
+}
+
+// newProblem returns the problem details for the error and its joined errors
+func newProblem(err error) *Problem {
+	p := classProblem(err)
+	for _, e := range joinedErrors(err) {
+		p.Errors = append(p.Errors, newProblem(e))
+	}
+	return p
+}
+
+// classProblem returns the problem details for the error without any
+// extension members other than the class
+func classProblem(err error) *Problem {
+	c := errdefs.ClassOf(err)
+	return &Problem{
+		Type:   ProblemTypePrefix + c.String(),
+		Title:  c.Err().Error(),
+		Status: ToHTTP(err),
+		Detail: err.Error(),
+		Class:  c.String(),
+	}
+}
+
+// joinedErrors returns the first list of joined errors found in the chain
+// of wrapped errors, ignoring collapsed errors such as stack traces.
+func joinedErrors(err error) []error {
+	for {
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Unwrap() []error }:
+			var errs []error
+			for _, je := range e.Unwrap() {
+				if _, ok := je.(types.CollapsibleError); !ok {
+					errs = append(errs, je)
+				}
+			}
+			if len(errs) == 1 {
+				// Only the collapsed errors were joined
+				err = errs[0]
+				continue
+			}
+			return errs
+		default:
+			return nil
+		}
+	}
+}
+
+// ToNative returns the error described by the problem. The class of the
+// error is taken from the class or type of the problem, falling back to the
+// status code for problems which were not created by this package. The
+// reason and typed errors of the problem are restored, such as the
+// violations returned by errdefs.FieldViolations,
+// errdefs.PreconditionViolations and errdefs.QuotaViolations, and each
+// errdefs.ResourceError.
+func (p *Problem) ToNative() error {
+	err := p.toNative()
+	if typed := p.typedErrors(); len(typed) > 0 {
+		err = types.CollapsedError(err, typed...)
+	}
+	if p.Reason != nil {
+		err = errdefs.WithReason(err, p.Reason.Domain, p.Reason.Reason, p.Reason.Metadata)
+	}
+	return err
+}
+
+// typedErrors returns the typed errors carried by the extension members
+func (p *Problem) typedErrors() []error {
+	var errs []error
+	if len(p.InvalidParams) > 0 {
+		fields := make(errdefs.InvalidFields, len(p.InvalidParams))
+		for i, ip := range p.InvalidParams {
+			fields[i] = errdefs.FieldViolation{Field: ip.Name, Description: ip.Reason, Reason: ip.Code}
+		}
+		errs = append(errs, fields)
+	}
+	if len(p.Preconditions) > 0 {
+		preconditions := make(errdefs.FailedPreconditions, len(p.Preconditions))
+		for i, pc := range p.Preconditions {
+			preconditions[i] = errdefs.PreconditionViolation(pc)
+		}
+		errs = append(errs, preconditions)
+	}
+	if len(p.Quotas) > 0 {
+		quotas := make(errdefs.ExceededQuotas, len(p.Quotas))
+		for i, q := range p.Quotas {
+			quotas[i] = errdefs.QuotaViolation(q)
+		}
+		errs = append(errs, quotas)
+	}
+	for _, r := range p.Resources {
+		rerr := &errdefs.ResourceError{Type: r.Type, Name: r.Name, Owner: r.Owner, Operation: r.Operation}
+		if c, err := errdefs.ParseClass(r.Class); err == nil {
+			rerr.Err = c.Err()
+		} else {
+			rerr.Err = p.class()
+		}
+		errs = append(errs, rerr)
+	}
+	return errs
+}
+
+func (p *Problem) toNative() error {
+	if len(p.Errors) > 0 {
+		errs := make([]error, 0, len(p.Errors))
+		for _, np := range p.Errors {
+			errs = append(errs, np.ToNative())
+		}
+		msg := p.Detail
+		if msg == "" {
+			msg = errors.Join(errs...).Error()
+		}
+		return joinedError{msg: msg, errs: errs}
+	}
+
+	return withDescription(p.class(), p.Detail)
+}
+
+func (p *Problem) class() error {
+	c, err := errdefs.ParseClass(p.Class)
+	if err != nil && strings.HasPrefix(p.Type, ProblemTypePrefix) {
+		c, err = errdefs.ParseClass(strings.TrimPrefix(p.Type, ProblemTypePrefix))
+	}
+	switch {
+	case err == nil && p.Status == 0:
+		return c.Err()
+	case err == nil:
+		return ToNativeClass(p.Status, c)
+	case p.Status != 0:
+		return ToNative(p.Status)
+	default:
+		return errdefs.ErrUnknown
+	}
+}
+
+// WriteProblem writes the error to the response as problem details using
+// the status code returned by ToHTTP, see NewProblem. The request, if
+// provided, is used as the problem instance. A delay carried by the error,
+// see errdefs.WithRetryAfter, is sent in the Retry-After header.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, err, ProblemContentType, false, nil)
+}
+
+// writeProblem writes the error as problem details with the content type,
+// using the internal message of the error when trusted, with the redactor
+// applied
+func writeProblem(w http.ResponseWriter, r *http.Request, err error, contentType string, trusted bool, rd *redact.Redactor) {
+	p := problemFor(err, trusted)
+	if rd != nil {
+		p.redact(rd.String)
+	}
+	if r != nil && r.URL != nil {
+		p.Instance = r.URL.RequestURI()
+	}
+	setRetryAfter(w.Header(), err)
+	writeJSON(w, contentType, p)
+}
+
+// redact applies the function to the detail and descriptions of the problem
+// and the problems of its joined errors
+func (p *Problem) redact(fn func(string) string) {
+	p.Detail = fn(p.Detail)
+	for _, e := range p.Errors {
+		e.redact(fn)
+	}
+	for i := range p.InvalidParams {
+		p.InvalidParams[i].Reason = fn(p.InvalidParams[i].Reason)
+	}
+	for i := range p.Preconditions {
+		p.Preconditions[i].Description = fn(p.Preconditions[i].Description)
+	}
+	for i := range p.Quotas {
+		p.Quotas[i].Description = fn(p.Quotas[i].Description)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, contentType string, p *Problem) {
+	h := w.Header()
+	h.Set(ClassHeader, p.Class)
+	h.Set("Content-Type", contentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	if p.Status != http.StatusNotModified {
+		json.NewEncoder(w).Encode(p)
+	}
+}
+
+// withDescription returns an error for the class with the given message,
+// avoiding repeating the class message at the end of the description.
+func withDescription(cls error, desc string) error {
+	clss := cls.Error()
+	if desc == "" || desc == clss {
+		return cls
+	}
+	if msg := strings.TrimSuffix(desc, ": "+clss); msg != desc {
+		return fmt.Errorf("%s: %w", msg, cls)
+	}
+	if wm, ok := cls.(interface{ WithMessage(string) error }); ok {
+		return wm.WithMessage(desc)
+	}
+	return fmt.Errorf("%s: %w", desc, cls)
+}
+
+// joinedError is a joined error which keeps the message it was sent with
+type joinedError struct {
+	msg  string
+	errs []error
+}
+
+func (e joinedError) Error() string {
+	return e.msg
+}
+
+func (e joinedError) Unwrap() []error {
+	return e.errs
+}
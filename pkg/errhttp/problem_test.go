@@ -0,0 +1,381 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/stack"
+)
+
+func TestProblemRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		input error
+		cause error
+		str   string
+	}{
+		{
+			input: errdefs.ErrNotFound,
+			cause: errdefs.ErrNotFound,
+		},
+		{
+			input: fmt.Errorf("image %q: %w", "docker.io/library/busybox", errdefs.ErrNotFound),
+			cause: errdefs.ErrNotFound,
+			str:   `image "docker.io/library/busybox": not found`,
+		},
+		{
+			input: errdefs.ErrAborted.WithMessage("transaction aborted"),
+			cause: errdefs.ErrAborted,
+			str:   "transaction aborted",
+		},
+		{
+			input: fmt.Errorf("lease: %w", errdefs.ErrAlreadyExists),
+			cause: errdefs.ErrAlreadyExists,
+			str:   "lease: already exists",
+		},
+		{
+			input: errdefs.ErrDataLoss,
+			cause: errdefs.ErrDataLoss,
+		},
+		{
+			input: fmt.Errorf("waiting: %w", context.DeadlineExceeded),
+			cause: context.DeadlineExceeded,
+			str:   "waiting: context deadline exceeded",
+		},
+		{
+			input: errors.New("unknown to package"),
+			cause: errdefs.ErrUnknown,
+			str:   "unknown to package",
+		},
+		{
+			input: fmt.Errorf("odd response: %w", ToNative(http.StatusTeapot)),
+			cause: ToNative(http.StatusTeapot),
+			str:   "odd response: unexpected status 418",
+		},
+		{
+			input: stack.Join(fmt.Errorf("with stack: %w", errdefs.ErrUnavailable)),
+			cause: errdefs.ErrUnavailable,
+			str:   "with stack: unavailable",
+		},
+	} {
+		t.Run(tc.input.Error(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			WriteProblem(rec, httptest.NewRequest(http.MethodGet, "/v1/images?all=1", nil), tc.input)
+			resp := rec.Result()
+			if ct := resp.Header.Get("Content-Type"); ct != ProblemContentType {
+				t.Fatalf("unexpected content type %q", ct)
+			}
+			if resp.StatusCode != ToHTTP(tc.input) {
+				t.Fatalf("unexpected status %d", resp.StatusCode)
+			}
+			t.Logf("body: %s", rec.Body.String())
+
+			var p Problem
+			if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Instance != "/v1/images?all=1" {
+				t.Fatalf("unexpected instance %q", p.Instance)
+			}
+			if len(p.Errors) != 0 {
+				t.Fatalf("unexpected nested errors: %v", p.Errors)
+			}
+
+			ferr := p.ToNative()
+			if !errors.Is(ferr, tc.cause) {
+				t.Fatalf("unexpected cause: !errors.Is(%v, %v)", ferr, tc.cause)
+			}
+			if errdefs.ClassOf(ferr) != errdefs.ClassOf(tc.input) {
+				t.Fatalf("unexpected class %v", errdefs.ClassOf(ferr))
+			}
+			expected := tc.str
+			if expected == "" {
+				expected = tc.cause.Error()
+			}
+			if ferr.Error() != expected {
+				t.Fatalf("unexpected string: %q != %q", ferr.Error(), expected)
+			}
+		})
+	}
+}
+
+func TestProblemNested(t *testing.T) {
+	err := fmt.Errorf("removing images: %w", errors.Join(
+		fmt.Errorf("image a: %w", errdefs.ErrNotFound),
+		fmt.Errorf("image b: %w", errdefs.ErrFailedPrecondition),
+		errors.Join(errdefs.ErrPermissionDenied, errdefs.ErrDataLoss),
+	))
+
+	b, jerr := json.Marshal(NewProblem(err))
+	if jerr != nil {
+		t.Fatal(jerr)
+	}
+	var p Problem
+	if jerr := json.Unmarshal(b, &p); jerr != nil {
+		t.Fatal(jerr)
+	}
+	if len(p.Errors) != 3 || len(p.Errors[2].Errors) != 2 {
+		t.Fatalf("unexpected nested errors: %s", b)
+	}
+
+	ferr := p.ToNative()
+	if ferr.Error() != err.Error() {
+		t.Fatalf("unexpected string: %q != %q", ferr.Error(), err.Error())
+	}
+	for _, check := range []func(error) bool{
+		errdefs.IsNotFound,
+		errdefs.IsFailedPrecondition,
+		errdefs.IsPermissionDenied,
+		errdefs.IsDataLoss,
+	} {
+		if check(err) != check(ferr) {
+			t.Fatalf("class check does not match for %v", ferr)
+		}
+	}
+	if errdefs.Resolve(ferr) != errdefs.ErrNotFound {
+		t.Fatalf("unexpected resolved error %v", errdefs.Resolve(ferr))
+	}
+}
+
+func TestProblemInvalidParams(t *testing.T) {
+	fields := errdefs.InvalidFields{
+		{Field: "image", Description: "must not be empty", Reason: "REQUIRED"},
+		{Field: "mounts[1].type", Description: `unsupported type "nfs"`},
+	}
+	for _, err := range []error{
+		fmt.Errorf("create container: %w", fields),
+		errors.Join(fields[0], fmt.Errorf("mounts: %w", fields[1])),
+	} {
+		rec := httptest.NewRecorder()
+		WriteProblem(rec, nil, err)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("unexpected status %d", rec.Code)
+		}
+
+		var raw struct {
+			InvalidParams []map[string]string `json:"invalid-params"`
+			Errors        []map[string]any    `json:"errors"`
+		}
+		if jerr := json.Unmarshal(rec.Body.Bytes(), &raw); jerr != nil {
+			t.Fatal(jerr)
+		}
+		if len(raw.InvalidParams) != 2 || raw.InvalidParams[0]["name"] != "image" || raw.InvalidParams[0]["reason"] != "must not be empty" || raw.InvalidParams[0]["code"] != "REQUIRED" {
+			t.Fatalf("unexpected invalid params: %s", rec.Body.Bytes())
+		}
+		for _, np := range raw.Errors {
+			if _, ok := np["invalid-params"]; ok {
+				t.Fatalf("invalid params repeated in nested problem: %s", rec.Body.Bytes())
+			}
+		}
+
+		resp := rec.Result()
+		ferr := FromResponse(resp)
+		if !errdefs.IsInvalidArgument(ferr) || ferr.Error() != err.Error() {
+			t.Fatalf("unexpected error %v", ferr)
+		}
+		if v := errdefs.FieldViolations(ferr); !reflect.DeepEqual(v, fields) {
+			t.Fatalf("unexpected violations %#v", v)
+		}
+	}
+}
+
+func TestProblemPreconditions(t *testing.T) {
+	preconditions := errdefs.FailedPreconditions{
+		{Type: "IN_USE", Subject: "snapshot/sha256:abc", Description: "in use by 3 containers"},
+	}
+	err := fmt.Errorf("remove snapshot: %w", preconditions)
+
+	rec := httptest.NewRecorder()
+	WriteProblem(rec, nil, err)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	var raw struct {
+		Preconditions []map[string]string `json:"preconditions"`
+	}
+	if jerr := json.Unmarshal(rec.Body.Bytes(), &raw); jerr != nil {
+		t.Fatal(jerr)
+	}
+	if len(raw.Preconditions) != 1 || raw.Preconditions[0]["type"] != "IN_USE" || raw.Preconditions[0]["subject"] != "snapshot/sha256:abc" {
+		t.Fatalf("unexpected preconditions: %s", rec.Body.Bytes())
+	}
+
+	ferr := FromResponse(rec.Result())
+	if !errdefs.IsFailedPrecondition(ferr) || ferr.Error() != err.Error() {
+		t.Fatalf("unexpected error %v", ferr)
+	}
+	var target errdefs.FailedPreconditions
+	if !errors.As(ferr, &target) || !reflect.DeepEqual(target, preconditions) {
+		t.Fatalf("errors.As should match the violations: %v", ferr)
+	}
+}
+
+func TestProblemQuotas(t *testing.T) {
+	quotas := errdefs.ExceededQuotas{
+		{Subject: "namespace:default", Limit: "snapshots", Current: 100, Max: 100},
+	}
+	err := errdefs.WithRetryAfter(fmt.Errorf("prepare: %w", quotas), 30*time.Second)
+
+	rec := httptest.NewRecorder()
+	WriteProblem(rec, nil, err)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	if ra := rec.Header().Get("Retry-After"); ra != "30" {
+		t.Fatalf("unexpected Retry-After %q", ra)
+	}
+	var raw struct {
+		Quotas []map[string]any `json:"quotas"`
+	}
+	if jerr := json.Unmarshal(rec.Body.Bytes(), &raw); jerr != nil {
+		t.Fatal(jerr)
+	}
+	if len(raw.Quotas) != 1 || raw.Quotas[0]["limit"] != "snapshots" || raw.Quotas[0]["max"] != float64(100) {
+		t.Fatalf("unexpected quotas: %s", rec.Body.Bytes())
+	}
+
+	ferr := FromResponse(rec.Result())
+	if !errdefs.IsResourceExhausted(ferr) || ferr.Error() != err.Error() {
+		t.Fatalf("unexpected error %v", ferr)
+	}
+	if v := errdefs.QuotaViolations(ferr); !reflect.DeepEqual(v, quotas) {
+		t.Fatalf("unexpected violations %#v", v)
+	}
+	if d, _ := errdefs.RetryAfter(ferr); d != 30*time.Second {
+		t.Fatalf("unexpected retry after %v", d)
+	}
+}
+
+func TestProblemResources(t *testing.T) {
+	image := errdefs.ResourceNotFound("image", "docker.io/library/redis:latest")
+	image.Owner = "default"
+	image.Operation = "get"
+	err := errors.Join(fmt.Errorf("pull: %w", image), errdefs.ResourceAlreadyExists("lease", "pull-1"))
+
+	rec := httptest.NewRecorder()
+	WriteProblem(rec, nil, err)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	var raw struct {
+		Resources []map[string]string `json:"resources"`
+	}
+	if jerr := json.Unmarshal(rec.Body.Bytes(), &raw); jerr != nil {
+		t.Fatal(jerr)
+	}
+	if len(raw.Resources) != 2 || raw.Resources[0]["name"] != "docker.io/library/redis:latest" || raw.Resources[1]["class"] != "ALREADY_EXISTS" {
+		t.Fatalf("unexpected resources: %s", rec.Body.Bytes())
+	}
+
+	ferr := FromResponse(rec.Result())
+	if !errdefs.IsNotFound(ferr) || ferr.Error() != err.Error() {
+		t.Fatalf("unexpected error %v", ferr)
+	}
+	var target *errdefs.ResourceError
+	if !errors.As(ferr, &target) || !reflect.DeepEqual(target, image) {
+		t.Fatalf("errors.As should match the resource: %#v", target)
+	}
+	if res := errdefs.ResourceErrors(ferr); !reflect.DeepEqual(res, errdefs.ResourceErrors(err)) {
+		t.Fatalf("unexpected resource errors %v", res)
+	}
+
+	var p Problem
+	if jerr := json.Unmarshal([]byte(`{"status":403,"resources":[{"type":"bucket","name":"layers"}]}`), &p); jerr != nil {
+		t.Fatal(jerr)
+	}
+	if !errors.As(p.ToNative(), &target) || !errdefs.IsPermissionDenied(target) {
+		t.Fatalf("resource without class should use problem class: %#v", target)
+	}
+}
+
+func TestProblemReason(t *testing.T) {
+	md := map[string]string{"lease": "pull-1"}
+	err := fmt.Errorf("commit: %w", errdefs.WithReason(errdefs.ErrFailedPrecondition.WithMessage("lease expired"), "containerd.io", "LEASE_EXPIRED", md))
+
+	rec := httptest.NewRecorder()
+	WriteProblem(rec, nil, err)
+	var raw struct {
+		Reason map[string]any `json:"reason"`
+	}
+	if jerr := json.Unmarshal(rec.Body.Bytes(), &raw); jerr != nil {
+		t.Fatal(jerr)
+	}
+	if raw.Reason["domain"] != "containerd.io" || raw.Reason["reason"] != "LEASE_EXPIRED" {
+		t.Fatalf("unexpected reason: %s", rec.Body.Bytes())
+	}
+
+	ferr := FromResponse(rec.Result())
+	if !errdefs.IsFailedPrecondition(ferr) || ferr.Error() != err.Error() {
+		t.Fatalf("unexpected error %v", ferr)
+	}
+	r, ok := errdefs.ReasonOf(ferr)
+	if !ok || r.Reason != "LEASE_EXPIRED" || !reflect.DeepEqual(r.Metadata, md) {
+		t.Fatalf("unexpected reason %+v", r)
+	}
+}
+
+func TestProblemForeign(t *testing.T) {
+	for _, tc := range []struct {
+		body  string
+		cause error
+		str   string
+	}{
+		{
+			body:  `{"type":"about:blank","title":"Not Found","status":404,"detail":"no such widget"}`,
+			cause: errdefs.ErrNotFound,
+			str:   "no such widget",
+		},
+		{
+			body:  `{"type":"urn:containerd:errdefs:ABORTED","status":409}`,
+			cause: errdefs.ErrAborted,
+		},
+		{
+			body:  `{"class":"OUT_OF_RANGE","detail":"offset past end"}`,
+			cause: errdefs.ErrOutOfRange,
+			str:   "offset past end",
+		},
+		{
+			body:  `{"title":"Something odd"}`,
+			cause: errdefs.ErrUnknown,
+		},
+	} {
+		var p Problem
+		if err := json.Unmarshal([]byte(tc.body), &p); err != nil {
+			t.Fatal(err)
+		}
+		ferr := p.ToNative()
+		if !errors.Is(ferr, tc.cause) {
+			t.Errorf("%s: unexpected cause: !errors.Is(%v, %v)", tc.body, ferr, tc.cause)
+		}
+		expected := tc.str
+		if expected == "" {
+			expected = tc.cause.Error()
+		}
+		if ferr.Error() != expected {
+			t.Errorf("%s: unexpected string: %q != %q", tc.body, ferr.Error(), expected)
+		}
+	}
+}
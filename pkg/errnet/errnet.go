@@ -0,0 +1,107 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package errnet provides utility functions for classifying network errors,
+// such as dial failures, DNS errors, TLS certificate verification failures
+// and timeouts.
+//
+// Use Wrap on errors returned from network clients so that the errdefs
+// functions, along with errgrpc and errhttp, see the class of the error
+// while the original error is kept.
+package errnet
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/internal/types"
+)
+
+// Classify returns the errdefs class of a network error. False is returned
+// if the error is not recognized. Network errors are classified as follows:
+//
+//	context.Canceled                                Canceled
+//	context.DeadlineExceeded                        DeadlineExceeded
+//	x509 and TLS certificate verification errors   Unauthenticated
+//	*net.DNSError for a host which is not found     NotFound
+//	net.Error timeouts                              DeadlineExceeded
+//	connection refused, reset or aborted            Unavailable
+//	network or host unreachable                     Unavailable
+//	other *net.DNSError and *net.OpError            Unavailable
+func Classify(err error) (errdefs.Class, bool) {
+	if err == nil {
+		return errdefs.ClassUnknown, false
+	}
+
+	// Dials and lookups stopped by their context return network errors
+	// which also match the context error.
+	if errors.Is(err, context.Canceled) {
+		return errdefs.ClassCanceled, true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errdefs.ClassDeadlineExceeded, true
+	}
+
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		invalidErr   x509.CertificateInvalidError
+		hostnameErr  x509.HostnameError
+		rootsErr     x509.SystemRootsError
+	)
+	if errors.As(err, &verifyErr) || errors.As(err, &authorityErr) || errors.As(err, &invalidErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &rootsErr) {
+		return errdefs.ClassUnauthenticated, true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return errdefs.ClassNotFound, true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errdefs.ClassDeadlineExceeded, true
+	}
+
+	for _, errno := range unavailableErrnos {
+		if errors.Is(err, errno) {
+			return errdefs.ClassUnavailable, true
+		}
+	}
+
+	var opErr *net.OpError
+	if dnsErr != nil || errors.As(err, &opErr) {
+		return errdefs.ClassUnavailable, true
+	}
+
+	return errdefs.ClassUnknown, false
+}
+
+// Wrap returns the error marked with its class when it is a network error,
+// otherwise the error is returned unchanged. The returned error has the same
+// message and still matches the original error using errors.Is and
+// errors.As.
+func Wrap(err error) error {
+	if c, ok := Classify(err); ok {
+		return types.WithClass(err, c)
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errnet
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/errgrpc"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		err   error
+		class errdefs.Class
+		ok    bool
+	}{
+		{nil, errdefs.ClassUnknown, false},
+		{errors.New("other"), errdefs.ClassUnknown, false},
+		{&net.DNSError{Err: "no such host", Name: "registry.invalid", IsNotFound: true}, errdefs.ClassNotFound, true},
+		{&net.DNSError{Err: "i/o timeout", Name: "registry.example", IsTimeout: true}, errdefs.ClassDeadlineExceeded, true},
+		{&net.DNSError{Err: "server misbehaving", Name: "registry.example"}, errdefs.ClassUnavailable, true},
+		{fmt.Errorf("verify: %w", x509.UnknownAuthorityError{}), errdefs.ClassUnauthenticated, true},
+		{x509.HostnameError{Certificate: &x509.Certificate{}, Host: "example.com"}, errdefs.ClassUnauthenticated, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: x509.CertificateInvalidError{Reason: x509.Expired}}, errdefs.ClassUnauthenticated, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: context.Canceled}, errdefs.ClassCanceled, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: context.DeadlineExceeded}, errdefs.ClassDeadlineExceeded, true},
+	} {
+		c, ok := Classify(tc.err)
+		if c != tc.class || ok != tc.ok {
+			t.Errorf("Classify(%v) = %v, %t; expected %v, %t", tc.err, c, ok, tc.class, tc.ok)
+		}
+	}
+}
+
+func TestWrapDial(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	_, err = net.Dial("tcp", addr)
+	if err == nil {
+		t.Skip("dial to closed listener unexpectedly succeeded")
+	}
+	werr := Wrap(err)
+	if !errdefs.IsUnavailable(werr) {
+		t.Fatalf("expected unavailable, got %v", werr)
+	}
+	if werr.Error() != err.Error() {
+		t.Fatalf("unexpected message %q", werr.Error())
+	}
+	var opErr *net.OpError
+	if !errors.As(werr, &opErr) || opErr.Op != "dial" {
+		t.Fatalf("errors.As should match *net.OpError: %v", werr)
+	}
+	if st, _ := status.FromError(errgrpc.ToGRPC(werr)); st.Code() != codes.Unavailable {
+		t.Fatalf("unexpected gRPC code %v", st.Code())
+	}
+}
+
+func TestWrapCanceledDial(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var d net.Dialer
+	_, err := d.DialContext(ctx, "tcp", "127.0.0.1:1")
+	if err == nil {
+		t.Fatal("expected dial to be canceled")
+	}
+	werr := Wrap(err)
+	if c := errdefs.ClassOf(werr); c != errdefs.ClassCanceled {
+		t.Fatalf("expected canceled, got %v for %v", c, werr)
+	}
+	if errdefs.IsRetryable(werr, true) {
+		t.Fatalf("canceled dial should not be retryable: %v", werr)
+	}
+}
+
+func TestWrapTimeout(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	c1.SetReadDeadline(time.Now().Add(-time.Second))
+	_, err := c1.Read(make([]byte, 1))
+	werr := Wrap(err)
+	if !errdefs.IsDeadlineExceeded(werr) {
+		t.Fatalf("expected deadline exceeded, got %v", werr)
+	}
+	var netErr net.Error
+	if !errors.As(werr, &netErr) || !netErr.Timeout() {
+		t.Fatalf("errors.As should match net.Error: %v", werr)
+	}
+}
+
+func TestWrapTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err := http.Get(srv.URL)
+	if err == nil {
+		t.Fatal("expected certificate verification error")
+	}
+	werr := Wrap(err)
+	if !errdefs.IsUnauthorized(werr) {
+		t.Fatalf("expected unauthenticated, got %v", werr)
+	}
+	var authorityErr x509.UnknownAuthorityError
+	if !errors.As(werr, &authorityErr) {
+		t.Fatalf("errors.As should match x509.UnknownAuthorityError: %v", werr)
+	}
+}
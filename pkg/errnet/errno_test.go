@@ -0,0 +1,41 @@
+//go:build !plan9
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errnet
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/containerd/errdefs"
+)
+
+func TestClassifyErrno(t *testing.T) {
+	for _, err := range []error{
+		&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+		&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+		fmt.Errorf("pulling: %w", syscall.ECONNRESET),
+		fmt.Errorf("dial: %w", syscall.EHOSTUNREACH),
+	} {
+		if c, ok := Classify(err); c != errdefs.ClassUnavailable || !ok {
+			t.Errorf("Classify(%v) = %v, %t; expected %v, true", err, c, ok, errdefs.ClassUnavailable)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package errregistry provides utility functions for translating errors to
+// and from the error format of the OCI distribution specification, used by
+// container registries.
+//
+// The functions FromResponse and WriteError can be used to map client-side
+// and server-side errors to the correct types.
+package errregistry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/errhttp"
+)
+
+// ErrorCode is an error code returned by a registry
+type ErrorCode string
+
+// Error codes defined by the OCI distribution specification
+const (
+	ErrorCodeBlobUnknown         ErrorCode = "BLOB_UNKNOWN"
+	ErrorCodeBlobUploadInvalid   ErrorCode = "BLOB_UPLOAD_INVALID"
+	ErrorCodeBlobUploadUnknown   ErrorCode = "BLOB_UPLOAD_UNKNOWN"
+	ErrorCodeDigestInvalid       ErrorCode = "DIGEST_INVALID"
+	ErrorCodeManifestBlobUnknown ErrorCode = "MANIFEST_BLOB_UNKNOWN"
+	ErrorCodeManifestInvalid     ErrorCode = "MANIFEST_INVALID"
+	ErrorCodeManifestUnknown     ErrorCode = "MANIFEST_UNKNOWN"
+	ErrorCodeNameInvalid         ErrorCode = "NAME_INVALID"
+	ErrorCodeNameUnknown         ErrorCode = "NAME_UNKNOWN"
+	ErrorCodeSizeInvalid         ErrorCode = "SIZE_INVALID"
+	ErrorCodeUnauthorized        ErrorCode = "UNAUTHORIZED"
+	ErrorCodeDenied              ErrorCode = "DENIED"
+	ErrorCodeUnsupported         ErrorCode = "UNSUPPORTED"
+	ErrorCodeTooManyRequests     ErrorCode = "TOOMANYREQUESTS"
+
+	// ErrorCodeUnknown is not defined by the specification but is used
+	// by registries for errors which have no other code.
+	ErrorCodeUnknown ErrorCode = "UNKNOWN"
+)
+
+// Class returns the errdefs class for the error code. Codes which are not
+// defined by the specification are ClassUnknown.
+func (c ErrorCode) Class() errdefs.Class {
+	switch c {
+	case ErrorCodeBlobUnknown, ErrorCodeBlobUploadUnknown, ErrorCodeManifestBlobUnknown,
+		ErrorCodeManifestUnknown, ErrorCodeNameUnknown:
+		return errdefs.ClassNotFound
+	case ErrorCodeBlobUploadInvalid, ErrorCodeDigestInvalid, ErrorCodeManifestInvalid,
+		ErrorCodeNameInvalid, ErrorCodeSizeInvalid:
+		return errdefs.ClassInvalidArgument
+	case ErrorCodeUnauthorized:
+		return errdefs.ClassUnauthenticated
+	case ErrorCodeDenied:
+		return errdefs.ClassPermissionDenied
+	case ErrorCodeUnsupported:
+		return errdefs.ClassNotImplemented
+	case ErrorCodeTooManyRequests:
+		return errdefs.ClassResourceExhausted
+	default:
+		return errdefs.ClassUnknown
+	}
+}
+
+// WithMessage returns a registry error with the code and message
+func (c ErrorCode) WithMessage(msg string) error {
+	return &Error{Code: c, Message: msg}
+}
+
+// Error is a single error returned by a registry. The error matches the
+// errdefs class of its code, or of the response status code for codes
+// which are not defined by the specification.
+type Error struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message,omitempty"`
+	Detail  any       `json:"detail,omitempty"`
+
+	// status is the status code of the response the error was decoded from
+	status int
+}
+
+func (e *Error) Error() string {
+	desc := strings.ToLower(strings.ReplaceAll(string(e.Code), "_", " "))
+	if e.Message == "" || strings.EqualFold(e.Message, desc) {
+		return desc
+	}
+	return desc + ": " + e.Message
+}
+
+// Unwrap returns the error for the class of the error
+func (e *Error) Unwrap() error {
+	if c := e.Code.Class(); c != errdefs.ClassUnknown {
+		return c.Err()
+	}
+	if e.status != 0 {
+		return errhttp.ToNative(e.status)
+	}
+	return errdefs.ErrUnknown
+}
+
+// Errors is a list of errors returned together by a registry
+type Errors []*Error
+
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (errs Errors) Unwrap() []error {
+	u := make([]error, len(errs))
+	for i, err := range errs {
+		u[i] = err
+	}
+	return u
+}
+
+type errorBody struct {
+	Errors Errors `json:"errors"`
+}
+
+// maxErrorBody is the maximum number of bytes read from the body of an
+// unsuccessful response
+const maxErrorBody = 64 << 10
+
+// FromResponse returns the error for an unsuccessful registry response by
+// decoding the errors in the response body. A nil error is returned for
+// successful responses. A single error is returned as an *Error and multiple
+// errors as Errors.
+//
+// The body is read, up to a limit, but not closed. When the body does not
+// contain registry errors, the result of errhttp.FromResponse is returned.
+func FromResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	var b []byte
+	if resp.Body != nil {
+		b, _ = io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	}
+
+	var (
+		body errorBody
+		errs Errors
+	)
+	if json.Unmarshal(b, &body) == nil {
+		for _, err := range body.Errors {
+			if err != nil {
+				err.status = resp.StatusCode
+				errs = append(errs, err)
+			}
+		}
+	}
+	switch len(errs) {
+	case 0:
+		r := *resp
+		r.Body = io.NopCloser(bytes.NewReader(b))
+		if r.Header.Get("Content-Type") == "" && json.Valid(b) {
+			r.Header = http.Header{"Content-Type": {"application/json"}}
+			for k, v := range resp.Header {
+				r.Header[k] = v
+			}
+		}
+		return errhttp.FromResponse(&r)
+	case 1:
+		return errs[0]
+	default:
+		return errs
+	}
+}
+
+// WriteError writes the error to the response in the format defined by the
+// OCI distribution specification, using the status code from errhttp.ToHTTP.
+// Registry errors contained in the error are written with their code, other
+// errors are written with a code matching their class.
+func WriteError(w http.ResponseWriter, err error) {
+	body := errorBody{Errors: registryErrors(err)}
+	if len(body.Errors) == 0 {
+		body.Errors = Errors{{Code: codeOf(errdefs.ClassOf(err)), Message: err.Error()}}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(errhttp.ToHTTP(err))
+	json.NewEncoder(w).Encode(body)
+}
+
+// registryErrors returns all the registry errors in the error tree
+func registryErrors(err error) Errors {
+	switch e := err.(type) {
+	case *Error:
+		return Errors{e}
+	case Errors:
+		return e
+	case interface{ Unwrap() error }:
+		if u := e.Unwrap(); u != nil {
+			return registryErrors(u)
+		}
+	case interface{ Unwrap() []error }:
+		var errs Errors
+		for _, u := range e.Unwrap() {
+			errs = append(errs, registryErrors(u)...)
+		}
+		return errs
+	}
+	return nil
+}
+
+// codeOf returns the error code for errors of the class which are not
+// registry errors
+func codeOf(c errdefs.Class) ErrorCode {
+	switch c {
+	case errdefs.ClassNotFound:
+		return ErrorCodeNameUnknown
+	case errdefs.ClassUnauthenticated:
+		return ErrorCodeUnauthorized
+	case errdefs.ClassPermissionDenied:
+		return ErrorCodeDenied
+	case errdefs.ClassNotImplemented:
+		return ErrorCodeUnsupported
+	case errdefs.ClassResourceExhausted:
+		return ErrorCodeTooManyRequests
+	default:
+		return ErrorCodeUnknown
+	}
+}
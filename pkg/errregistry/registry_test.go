@@ -0,0 +1,199 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errregistry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/containerd/errdefs"
+)
+
+func TestFromResponse(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+		cause  error
+		str    string
+		code   ErrorCode
+	}{
+		{
+			name:   "manifest unknown",
+			status: http.StatusNotFound,
+			body:   `{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown","detail":{"Tag":"latest"}}]}`,
+			cause:  errdefs.ErrNotFound,
+			str:    "manifest unknown",
+			code:   ErrorCodeManifestUnknown,
+		},
+		{
+			name:   "denied",
+			status: http.StatusForbidden,
+			body:   `{"errors":[{"code":"DENIED","message":"requested access to the resource is denied"}]}`,
+			cause:  errdefs.ErrPermissionDenied,
+			str:    "denied: requested access to the resource is denied",
+			code:   ErrorCodeDenied,
+		},
+		{
+			name:   "too many requests",
+			status: http.StatusTooManyRequests,
+			body:   `{"errors":[{"code":"TOOMANYREQUESTS","message":"pull rate limit"}]}`,
+			cause:  errdefs.ErrResourceExhausted,
+			str:    "toomanyrequests: pull rate limit",
+			code:   ErrorCodeTooManyRequests,
+		},
+		{
+			name:   "size invalid",
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"code":"SIZE_INVALID","message":"provided length did not match content length"}]}`,
+			cause:  errdefs.ErrInvalidArgument,
+			str:    "size invalid: provided length did not match content length",
+			code:   ErrorCodeSizeInvalid,
+		},
+		{
+			name:   "unknown code uses status",
+			status: http.StatusServiceUnavailable,
+			body:   `{"errors":[{"code":"UNAVAILABLE","message":"maintenance"}]}`,
+			cause:  errdefs.ErrUnavailable,
+			str:    "unavailable: maintenance",
+			code:   "UNAVAILABLE",
+		},
+		{
+			name:   "not registry error",
+			status: http.StatusUnauthorized,
+			body:   `authentication required`,
+			cause:  errdefs.ErrUnauthenticated,
+			str:    "authentication required",
+		},
+		{
+			name:   "empty errors",
+			status: http.StatusNotFound,
+			body:   `{"errors":[]}`,
+			cause:  errdefs.ErrNotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tc.status,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(tc.body)),
+			}
+			err := FromResponse(resp)
+			if !errors.Is(err, tc.cause) {
+				t.Fatalf("unexpected cause: !errors.Is(%v, %v)", err, tc.cause)
+			}
+			if errdefs.Resolve(err) != tc.cause {
+				t.Fatalf("unexpected resolved error %v", errdefs.Resolve(err))
+			}
+			expected := tc.str
+			if expected == "" {
+				expected = tc.cause.Error()
+			}
+			if err.Error() != expected {
+				t.Fatalf("unexpected string: %q != %q", err.Error(), expected)
+			}
+			var rerr *Error
+			if errors.As(err, &rerr) != (tc.code != "") {
+				t.Fatalf("unexpected registry error presence: %v", err)
+			}
+			if rerr != nil && rerr.Code != tc.code {
+				t.Fatalf("unexpected code %v", rerr.Code)
+			}
+		})
+	}
+
+	if err := FromResponse(&http.Response{StatusCode: http.StatusOK}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestFromResponseMultiple(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusUnauthorized,
+		Header:     http.Header{},
+		Body: io.NopCloser(strings.NewReader(`{"errors":[
+			{"code":"UNAUTHORIZED","message":"authentication required"},
+			{"code":"NAME_UNKNOWN","message":"repository name not known to registry"}
+		]}`)),
+	}
+	err := FromResponse(resp)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	if !errdefs.IsUnauthorized(err) || !errdefs.IsNotFound(err) {
+		t.Fatalf("classes not preserved: %v", err)
+	}
+	if errdefs.Resolve(err) != errdefs.ErrUnauthenticated {
+		t.Fatalf("unexpected resolved error %v", errdefs.Resolve(err))
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	for _, tc := range []struct {
+		input  error
+		status int
+		body   string
+	}{
+		{
+			input:  ErrorCodeBlobUnknown.WithMessage("blob unknown to registry"),
+			status: http.StatusNotFound,
+			body:   `{"errors":[{"code":"BLOB_UNKNOWN","message":"blob unknown to registry"}]}`,
+		},
+		{
+			input:  fmt.Errorf("resolving: %w", &Error{Code: ErrorCodeManifestUnknown, Message: "manifest unknown", Detail: map[string]string{"Tag": "v1"}}),
+			status: http.StatusNotFound,
+			body:   `{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown","detail":{"Tag":"v1"}}]}`,
+		},
+		{
+			input:  errors.Join(ErrorCodeDigestInvalid.WithMessage("bad digest"), ErrorCodeSizeInvalid.WithMessage("bad size")),
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"code":"DIGEST_INVALID","message":"bad digest"},{"code":"SIZE_INVALID","message":"bad size"}]}`,
+		},
+		{
+			input:  fmt.Errorf("quota: %w", errdefs.ErrResourceExhausted),
+			status: http.StatusTooManyRequests,
+			body:   `{"errors":[{"code":"TOOMANYREQUESTS","message":"quota: resource exhausted"}]}`,
+		},
+		{
+			input:  fmt.Errorf("disk failure: %w", errdefs.ErrInternal),
+			status: http.StatusInternalServerError,
+			body:   `{"errors":[{"code":"UNKNOWN","message":"disk failure: internal"}]}`,
+		},
+	} {
+		t.Run(tc.input.Error(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			WriteError(rec, tc.input)
+			if rec.Code != tc.status {
+				t.Fatalf("unexpected status %d", rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tc.body {
+				t.Fatalf("unexpected body %s", body)
+			}
+
+			err := FromResponse(rec.Result())
+			if errdefs.ClassOf(err) != errdefs.ClassOf(tc.input) {
+				t.Fatalf("class not preserved: %v != %v", errdefs.ClassOf(err), errdefs.ClassOf(tc.input))
+			}
+		})
+	}
+}
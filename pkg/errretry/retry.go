@@ -0,0 +1,164 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package errretry provides retrying of operations based on the class of
+// the errors they return.
+//
+// Whether an error is retried is decided by errdefs.IsRetryable. A delay
+// requested by the server, as returned by errdefs.RetryAfter, is used in
+// place of the backoff when present on the error.
+package errretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/containerd/errdefs"
+)
+
+// Policy configures how an operation is retried
+type Policy struct {
+	// MaxAttempts is the maximum number of times the operation is
+	// attempted, including the first attempt.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the limit on the delay between attempts. It does not
+	// limit delays requested by the server.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the backoff is increased by after each
+	// retry.
+	Multiplier float64
+
+	// Jitter is the fraction of the backoff by which each delay is
+	// randomly increased or decreased, between 0 and 1. No jitter is
+	// applied when zero.
+	Jitter float64
+
+	// Idempotent indicates the operation may be safely performed more than
+	// once, allowing more errors to be retried, see errdefs.IsRetryable.
+	Idempotent bool
+}
+
+// DefaultPolicy is the policy providing the values for the fields of a
+// policy which are not set
+var DefaultPolicy = Policy{
+	MaxAttempts:    4,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultPolicy.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultPolicy.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultPolicy.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultPolicy.Multiplier
+	}
+	p.Jitter = min(max(p.Jitter, 0), 1)
+	return p
+}
+
+// jitter returns the delay randomly adjusted by the jitter fraction
+func (p Policy) jitter(d time.Duration) time.Duration {
+	if p.Jitter == 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+}
+
+// Do calls op until it succeeds, returns an error which is not retryable or
+// the policy's maximum number of attempts is reached. Fields of the policy
+// which are not set are taken from DefaultPolicy. Errors of ClassCanceled
+// are never retried, see errdefs.IsRetryable.
+//
+// The delay between attempts grows exponentially from the initial backoff,
+// unless the error carries a delay requested by the server, see
+// errdefs.RetryAfter, in which case that delay is used. No further attempt
+// is made when the delay would exceed the deadline of the context.
+//
+// When only one attempt was made, its error is returned unchanged.
+// Otherwise, the errors from every attempt are joined, most recent first,
+// so that the returned error has the class of the final attempt while the
+// class of every other attempt can still be checked. When the context ends
+// while waiting to retry, the context error comes first.
+func Do(ctx context.Context, op func(context.Context) error, p Policy) error {
+	p = p.withDefaults()
+
+	var (
+		errs    []error
+		backoff = p.InitialBackoff
+	)
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+		if attempt >= p.MaxAttempts || !errdefs.IsRetryable(err, p.Idempotent) {
+			return joinAttempts(nil, errs)
+		}
+
+		delay, ok := errdefs.RetryAfter(err)
+		if !ok {
+			delay = p.jitter(backoff)
+		}
+		delay = max(delay, 0)
+		backoff = min(time.Duration(float64(backoff)*p.Multiplier), p.MaxBackoff)
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return joinAttempts(nil, errs)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return joinAttempts(ctx.Err(), errs)
+		case <-t.C:
+		}
+	}
+}
+
+// joinAttempts joins the errors from each attempt, most recent first,
+// preceded by the context error if set
+func joinAttempts(ctxErr error, errs []error) error {
+	if ctxErr == nil && len(errs) == 1 {
+		return errs[0]
+	}
+	joined := make([]error, 0, len(errs)+1)
+	if ctxErr != nil {
+		joined = append(joined, ctxErr)
+	}
+	for i := len(errs) - 1; i >= 0; i-- {
+		joined = append(joined, fmt.Errorf("attempt %d: %w", i+1, errs[i]))
+	}
+	return errors.Join(joined...)
+}
@@ -0,0 +1,171 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/containerd/errdefs"
+)
+
+var fast = Policy{
+	MaxAttempts:    4,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     4 * time.Millisecond,
+}
+
+// sequence returns an operation returning the errors in order, followed by
+// nil, and a pointer to the number of calls made
+func sequence(errs ...error) (func(context.Context) error, *int) {
+	var calls int
+	return func(context.Context) error {
+		calls++
+		if calls > len(errs) {
+			return nil
+		}
+		return errs[calls-1]
+	}, &calls
+}
+
+func TestDo(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		errs   []error
+		policy Policy
+		calls  int
+		class  errdefs.Class
+		failed bool
+	}{
+		{
+			name:  "success",
+			calls: 1,
+		},
+		{
+			name:  "retried",
+			errs:  []error{errdefs.ErrUnavailable, fmt.Errorf("busy: %w", errdefs.ErrResourceExhausted), errdefs.ErrAborted},
+			calls: 4,
+		},
+		{
+			name:   "terminal",
+			errs:   []error{errdefs.ErrUnavailable, errdefs.ErrNotFound, errdefs.ErrUnavailable},
+			calls:  2,
+			class:  errdefs.ClassNotFound,
+			failed: true,
+		},
+		{
+			name:   "canceled",
+			errs:   []error{context.Canceled},
+			policy: Policy{Idempotent: true},
+			calls:  1,
+			class:  errdefs.ClassCanceled,
+			failed: true,
+		},
+		{
+			name:   "not idempotent",
+			errs:   []error{context.DeadlineExceeded},
+			calls:  1,
+			class:  errdefs.ClassDeadlineExceeded,
+			failed: true,
+		},
+		{
+			name:   "idempotent",
+			errs:   []error{context.DeadlineExceeded, errors.New("connection reset")},
+			policy: Policy{Idempotent: true},
+			calls:  3,
+		},
+		{
+			name:   "max attempts",
+			errs:   []error{errdefs.ErrUnavailable, errdefs.ErrUnavailable, errdefs.ErrAborted},
+			policy: Policy{MaxAttempts: 3},
+			calls:  3,
+			class:  errdefs.ClassAborted,
+			failed: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := tc.policy
+			p.InitialBackoff, p.MaxBackoff = fast.InitialBackoff, fast.MaxBackoff
+			op, calls := sequence(tc.errs...)
+			err := Do(context.Background(), op, p)
+			if *calls != tc.calls {
+				t.Fatalf("expected %d calls, got %d", tc.calls, *calls)
+			}
+			if (err != nil) != tc.failed {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err != nil && errdefs.ClassOf(err) != tc.class {
+				t.Fatalf("expected class %v, got %v: %v", tc.class, errdefs.ClassOf(err), err)
+			}
+		})
+	}
+}
+
+func TestDoAttempts(t *testing.T) {
+	op, _ := sequence(errdefs.ErrUnavailable, errdefs.ErrResourceExhausted, errdefs.ErrNotFound)
+	err := Do(context.Background(), op, fast)
+	if !errdefs.IsNotFound(err) || !errdefs.IsUnavailable(err) || !errdefs.IsResourceExhausted(err) {
+		t.Fatalf("attempt errors not preserved: %v", err)
+	}
+	if errdefs.ClassOf(err) != errdefs.ClassNotFound {
+		t.Fatalf("final attempt should determine class, got %v", errdefs.ClassOf(err))
+	}
+	expected := "attempt 3: not found\nattempt 2: resource exhausted\nattempt 1: unavailable"
+	if err.Error() != expected {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+}
+
+func TestDoRetryAfter(t *testing.T) {
+	op, calls := sequence(errdefs.WithRetryAfter(errdefs.ErrResourceExhausted, 50*time.Millisecond))
+	start := time.Now()
+	if err := Do(context.Background(), op, fast); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Fatalf("server delay not honored, retried after %v", d)
+	}
+	if *calls != 2 {
+		t.Fatalf("unexpected calls %d", *calls)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	op, calls = sequence(errdefs.WithRetryAfter(errdefs.ErrUnavailable, time.Minute))
+	err := Do(ctx, op, fast)
+	if !errdefs.IsUnavailable(err) || *calls != 1 {
+		t.Fatalf("expected no retry past deadline, got %v after %d calls", err, *calls)
+	}
+}
+
+func TestDoContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	op := func(context.Context) error {
+		cancel()
+		return errdefs.ErrUnavailable
+	}
+	err := Do(ctx, op, Policy{InitialBackoff: time.Minute})
+	if !errdefs.IsCanceled(err) || !errdefs.IsUnavailable(err) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if errdefs.ClassOf(err) != errdefs.ClassCanceled {
+		t.Fatalf("context error should be first, got %v", err)
+	}
+}
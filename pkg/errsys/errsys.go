@@ -0,0 +1,147 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package errsys provides utility functions for classifying errors from the
+// operating system, such as syscall.Errno values and the io/fs errors.
+//
+// Use Wrap on errors returned from the os, io/fs and syscall packages so
+// that the errdefs functions, along with errgrpc and errhttp, see the class
+// of the error while the original error is kept. ToFS does the reverse,
+// allowing errdefs errors to be returned to users of the io/fs errors.
+package errsys
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/internal/types"
+)
+
+// Classify returns the errdefs class of an error from the operating system.
+// False is returned if the error is not recognized.
+//
+// The syscall.Errno values are classified as follows, with the errors from
+// io/fs and their equivalent values on other platforms mapped the same way:
+//
+//	ENOENT, ESRCH                      NotFound (fs.ErrNotExist)
+//	EEXIST                             AlreadyExists (fs.ErrExist)
+//	ENOTEMPTY                          FailedPrecondition
+//	EACCES, EPERM                      PermissionDenied (fs.ErrPermission)
+//	ENOSPC, EDQUOT, EMFILE, ENFILE     ResourceExhausted
+//	ENOMEM                             ResourceExhausted
+//	EBUSY, EAGAIN                      Unavailable
+//	EINVAL, ENAMETOOLONG               InvalidArgument (fs.ErrInvalid)
+//	ENOSYS, EOPNOTSUPP, ENOTSUP        NotImplemented (errors.ErrUnsupported)
+//	ETIMEDOUT                          DeadlineExceeded (os.ErrDeadlineExceeded)
+//	ECANCELED                          Canceled
+//	EIO                                DataLoss
+func Classify(err error) (errdefs.Class, bool) {
+	if err == nil {
+		return errdefs.ClassUnknown, false
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		for _, ec := range errnoClasses {
+			if ec.errno == errno {
+				return ec.class, true
+			}
+		}
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return errdefs.ClassNotFound, true
+	case errors.Is(err, fs.ErrExist):
+		return errdefs.ClassAlreadyExists, true
+	case errors.Is(err, fs.ErrPermission):
+		return errdefs.ClassPermissionDenied, true
+	case errors.Is(err, fs.ErrInvalid):
+		return errdefs.ClassInvalidArgument, true
+	case errors.Is(err, errors.ErrUnsupported):
+		return errdefs.ClassNotImplemented, true
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return errdefs.ClassDeadlineExceeded, true
+	}
+	return errdefs.ClassUnknown, false
+}
+
+// Wrap returns the error marked with its class when it is an error from the
+// operating system, otherwise the error is returned unchanged. The returned
+// error has the same message and still matches the original error using
+// errors.Is and errors.As.
+func Wrap(err error) error {
+	if c, ok := Classify(err); ok {
+		return types.WithClass(err, c)
+	}
+	return err
+}
+
+// ToFS returns the error so that it also matches the io/fs error for its
+// errdefs class using errors.Is, such as fs.ErrNotExist for not found errors.
+// This allows errors received from remote services, such as through
+// errgrpc.ToNative, to be returned from fs.FS implementations and other
+// os-style APIs. The errors are mapped as follows:
+//
+//	NotFound           fs.ErrNotExist
+//	AlreadyExists      fs.ErrExist
+//	PermissionDenied   fs.ErrPermission
+//	InvalidArgument    fs.ErrInvalid
+//	NotImplemented     errors.ErrUnsupported
+//
+// Other errors, and errors which already match the io/fs error, are
+// returned unchanged.
+func ToFS(err error) error {
+	var target error
+	switch errdefs.ClassOf(err) {
+	case errdefs.ClassNotFound:
+		target = fs.ErrNotExist
+	case errdefs.ClassAlreadyExists:
+		target = fs.ErrExist
+	case errdefs.ClassPermissionDenied:
+		target = fs.ErrPermission
+	case errdefs.ClassInvalidArgument:
+		target = fs.ErrInvalid
+	case errdefs.ClassNotImplemented:
+		target = errors.ErrUnsupported
+	default:
+		return err
+	}
+	if errors.Is(err, target) {
+		return err
+	}
+	return fsError{error: err, target: target}
+}
+
+// fsError is an error which also matches an io/fs error
+type fsError struct {
+	error
+	target error
+}
+
+func (e fsError) Is(target error) bool {
+	return target == e.target
+}
+
+func (e fsError) Unwrap() error {
+	return e.error
+}
+
+type errnoClass struct {
+	errno syscall.Errno
+	class errdefs.Class
+}
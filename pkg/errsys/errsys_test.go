@@ -0,0 +1,155 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errsys
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/errdefs/pkg/errgrpc"
+	"github.com/containerd/errdefs/pkg/errhttp"
+)
+
+func TestClassifyFS(t *testing.T) {
+	for _, tc := range []struct {
+		err   error
+		class errdefs.Class
+		ok    bool
+	}{
+		{nil, errdefs.ClassUnknown, false},
+		{errors.New("other"), errdefs.ClassUnknown, false},
+		{fs.ErrNotExist, errdefs.ClassNotFound, true},
+		{fs.ErrExist, errdefs.ClassAlreadyExists, true},
+		{fs.ErrPermission, errdefs.ClassPermissionDenied, true},
+		{fs.ErrInvalid, errdefs.ClassInvalidArgument, true},
+		{errors.ErrUnsupported, errdefs.ClassNotImplemented, true},
+		{os.ErrDeadlineExceeded, errdefs.ClassDeadlineExceeded, true},
+		{&fs.PathError{Op: "open", Path: "/missing", Err: fs.ErrNotExist}, errdefs.ClassNotFound, true},
+		{fmt.Errorf("wrapped: %w", fs.ErrExist), errdefs.ClassAlreadyExists, true},
+	} {
+		c, ok := Classify(tc.err)
+		if c != tc.class || ok != tc.ok {
+			t.Errorf("Classify(%v) = %v, %t; expected %v, %t", tc.err, c, ok, tc.class, tc.ok)
+		}
+	}
+}
+
+func TestWrapOpen(t *testing.T) {
+	_, err := os.Open(filepath.Join(t.TempDir(), "missing"))
+	if errdefs.IsNotFound(err) {
+		t.Fatal("unwrapped error unexpectedly not found")
+	}
+
+	werr := Wrap(err)
+	if !errdefs.IsNotFound(werr) {
+		t.Fatalf("expected not found, got %v", werr)
+	}
+	if errdefs.Resolve(werr) != errdefs.ErrNotFound {
+		t.Fatalf("unexpected resolved error %v", errdefs.Resolve(werr))
+	}
+	if werr.Error() != err.Error() {
+		t.Fatalf("unexpected message %q", werr.Error())
+	}
+	if !errors.Is(werr, fs.ErrNotExist) {
+		t.Fatal("errors.Is should match fs.ErrNotExist")
+	}
+	var perr *fs.PathError
+	if !errors.As(werr, &perr) {
+		t.Fatal("errors.As should match *fs.PathError")
+	}
+
+	if st, _ := status.FromError(errgrpc.ToGRPC(werr)); st.Code() != codes.NotFound {
+		t.Fatalf("unexpected gRPC code %v", st.Code())
+	}
+	if code := errhttp.ToHTTP(fmt.Errorf("loading: %w", werr)); code != 404 {
+		t.Fatalf("unexpected HTTP status %d", code)
+	}
+
+	if other := errors.New("other"); Wrap(other) != other {
+		t.Fatal("unrecognized errors should be returned unchanged")
+	}
+	if Wrap(nil) != nil {
+		t.Fatal("nil should be returned unchanged")
+	}
+}
+
+func TestToFS(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		target error
+	}{
+		{errdefs.ErrNotFound, fs.ErrNotExist},
+		{fmt.Errorf("content sha256:abc: %w", errdefs.ErrNotFound), fs.ErrNotExist},
+		{errdefs.ErrAlreadyExists.WithMessage("ref exists"), fs.ErrExist},
+		{errdefs.ErrPermissionDenied, fs.ErrPermission},
+		{errdefs.ErrInvalidArgument, fs.ErrInvalid},
+		{errdefs.ErrNotImplemented, errors.ErrUnsupported},
+		{errgrpc.ToNative(errgrpc.ToGRPC(errdefs.ErrNotFound)), fs.ErrNotExist},
+	} {
+		ferr := ToFS(tc.err)
+		if !errors.Is(ferr, tc.target) {
+			t.Errorf("%v: expected match for %v", tc.err, tc.target)
+		}
+		if ferr.Error() != tc.err.Error() {
+			t.Errorf("%v: unexpected message %q", tc.err, ferr.Error())
+		}
+		if errdefs.ClassOf(ferr) != errdefs.ClassOf(tc.err) {
+			t.Errorf("%v: class not preserved", tc.err)
+		}
+		if !errors.Is(ferr, tc.err) {
+			t.Errorf("%v: original error not matched", tc.err)
+		}
+		if errors.Is(ferr, fs.ErrClosed) {
+			t.Errorf("%v: unexpected match for other fs error", tc.err)
+		}
+	}
+
+	for _, err := range []error{nil, errdefs.ErrUnavailable, errors.New("other")} {
+		if ToFS(err) != err {
+			t.Errorf("%v: expected error to be unchanged", err)
+		}
+	}
+	if werr := Wrap(fs.ErrNotExist); ToFS(werr) != werr {
+		t.Error("error already matching fs error should be unchanged")
+	}
+}
+
+func TestToFSWalkDir(t *testing.T) {
+	fsys := remoteFS{}
+	err := fs.WalkDir(fsys, "missing", func(path string, d fs.DirEntry, err error) error {
+		return err
+	})
+	if !errors.Is(err, fs.ErrNotExist) || !errdefs.IsNotFound(err) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+// remoteFS is a file system which returns errors received over gRPC
+type remoteFS struct{}
+
+func (remoteFS) Open(name string) (fs.File, error) {
+	err := errgrpc.ToNative(errgrpc.ToGRPC(fmt.Errorf("content %s: %w", name, errdefs.ErrNotFound)))
+	return nil, &fs.PathError{Op: "open", Path: name, Err: ToFS(err)}
+}
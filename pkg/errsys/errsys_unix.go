@@ -0,0 +1,51 @@
+//go:build unix
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errsys
+
+import (
+	"syscall"
+
+	"github.com/containerd/errdefs"
+)
+
+// errnoClasses is a list rather than a map since some errno values
+// share the same number on some platforms, such as EAGAIN and EWOULDBLOCK
+var errnoClasses = []errnoClass{
+	{syscall.ENOENT, errdefs.ClassNotFound},
+	{syscall.ESRCH, errdefs.ClassNotFound},
+	{syscall.EEXIST, errdefs.ClassAlreadyExists},
+	{syscall.ENOTEMPTY, errdefs.ClassFailedPrecondition},
+	{syscall.EACCES, errdefs.ClassPermissionDenied},
+	{syscall.EPERM, errdefs.ClassPermissionDenied},
+	{syscall.ENOSPC, errdefs.ClassResourceExhausted},
+	{syscall.EDQUOT, errdefs.ClassResourceExhausted},
+	{syscall.EMFILE, errdefs.ClassResourceExhausted},
+	{syscall.ENFILE, errdefs.ClassResourceExhausted},
+	{syscall.ENOMEM, errdefs.ClassResourceExhausted},
+	{syscall.EBUSY, errdefs.ClassUnavailable},
+	{syscall.EAGAIN, errdefs.ClassUnavailable},
+	{syscall.EINVAL, errdefs.ClassInvalidArgument},
+	{syscall.ENAMETOOLONG, errdefs.ClassInvalidArgument},
+	{syscall.ENOSYS, errdefs.ClassNotImplemented},
+	{syscall.EOPNOTSUPP, errdefs.ClassNotImplemented},
+	{syscall.ENOTSUP, errdefs.ClassNotImplemented},
+	{syscall.ETIMEDOUT, errdefs.ClassDeadlineExceeded},
+	{syscall.ECANCELED, errdefs.ClassCanceled},
+	{syscall.EIO, errdefs.ClassDataLoss},
+}
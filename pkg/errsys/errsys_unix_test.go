@@ -0,0 +1,59 @@
+//go:build unix
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errsys
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/containerd/errdefs"
+)
+
+func TestClassifyErrno(t *testing.T) {
+	for _, tc := range []struct {
+		errno syscall.Errno
+		check func(error) bool
+	}{
+		{syscall.ENOENT, errdefs.IsNotFound},
+		{syscall.EEXIST, errdefs.IsAlreadyExists},
+		{syscall.ENOTEMPTY, errdefs.IsFailedPrecondition},
+		{syscall.EACCES, errdefs.IsPermissionDenied},
+		{syscall.EPERM, errdefs.IsPermissionDenied},
+		{syscall.ENOSPC, errdefs.IsResourceExhausted},
+		{syscall.EMFILE, errdefs.IsResourceExhausted},
+		{syscall.EDQUOT, errdefs.IsResourceExhausted},
+		{syscall.EBUSY, errdefs.IsUnavailable},
+		{syscall.EINVAL, errdefs.IsInvalidArgument},
+		{syscall.ENOSYS, errdefs.IsNotImplemented},
+		{syscall.EOPNOTSUPP, errdefs.IsNotImplemented},
+		{syscall.ETIMEDOUT, errdefs.IsDeadlineExceeded},
+		{syscall.ECANCELED, errdefs.IsCanceled},
+		{syscall.EIO, errdefs.IsDataLoss},
+	} {
+		err := Wrap(&os.SyscallError{Syscall: "test", Err: tc.errno})
+		if !tc.check(err) {
+			t.Errorf("%v: unexpected class %v", tc.errno, errdefs.ClassOf(err))
+		}
+		if !errors.Is(err, tc.errno) {
+			t.Errorf("%v: errors.Is should match the errno", tc.errno)
+		}
+	}
+}
@@ -12,5 +12,8 @@ require (
 
 require (
 	github.com/gogo/protobuf v1.3.2 // indirect
+	golang.org/x/net v0.28.0 // indirect
 	golang.org/x/sys v0.24.0 // indirect
+	golang.org/x/text v0.17.0 // indirect
 )
+
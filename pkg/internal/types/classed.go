@@ -0,0 +1,166 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package types
+
+import "github.com/containerd/errdefs"
+
+// WithClass returns the error marked with the interface of the error class
+// so that it resolves to the class. The returned error keeps the message of
+// the original error and unwraps to it. Errors are returned unchanged for
+// ClassUnknown.
+func WithClass(err error, c errdefs.Class) error {
+	switch c {
+	case errdefs.ClassInvalidArgument:
+		return invalidArgument{err}
+	case errdefs.ClassNotFound:
+		return notFound{err}
+	case errdefs.ClassAlreadyExists:
+		return alreadyExists{err}
+	case errdefs.ClassPermissionDenied:
+		return permissionDenied{err}
+	case errdefs.ClassResourceExhausted:
+		return resourceExhausted{err}
+	case errdefs.ClassFailedPrecondition:
+		return failedPrecondition{err}
+	case errdefs.ClassConflict:
+		return conflict{err}
+	case errdefs.ClassNotModified:
+		return notModified{err}
+	case errdefs.ClassAborted:
+		return aborted{err}
+	case errdefs.ClassOutOfRange:
+		return outOfRange{err}
+	case errdefs.ClassNotImplemented:
+		return notImplemented{err}
+	case errdefs.ClassInternal:
+		return internal{err}
+	case errdefs.ClassUnavailable:
+		return unavailable{err}
+	case errdefs.ClassDataLoss:
+		return dataLoss{err}
+	case errdefs.ClassUnauthenticated:
+		return unauthenticated{err}
+	case errdefs.ClassDeadlineExceeded:
+		return deadlineExceeded{err}
+	case errdefs.ClassCanceled:
+		return canceled{err}
+	default:
+		return err
+	}
+}
+
+type invalidArgument struct{ error }
+
+func (invalidArgument) InvalidParameter() {}
+
+func (e invalidArgument) Unwrap() error { return e.error }
+
+type notFound struct{ error }
+
+func (notFound) NotFound() {}
+
+func (e notFound) Unwrap() error { return e.error }
+
+type alreadyExists struct{ error }
+
+func (alreadyExists) AlreadyExists() {}
+
+func (e alreadyExists) Unwrap() error { return e.error }
+
+type permissionDenied struct{ error }
+
+func (permissionDenied) Forbidden() {}
+
+func (e permissionDenied) Unwrap() error { return e.error }
+
+type resourceExhausted struct{ error }
+
+func (resourceExhausted) ResourceExhausted() {}
+
+func (e resourceExhausted) Unwrap() error { return e.error }
+
+type failedPrecondition struct{ error }
+
+func (failedPrecondition) FailedPrecondition() {}
+
+func (e failedPrecondition) Unwrap() error { return e.error }
+
+type conflict struct{ error }
+
+func (conflict) Conflict() {}
+
+func (e conflict) Unwrap() error { return e.error }
+
+type notModified struct{ error }
+
+func (notModified) NotModified() {}
+
+func (e notModified) Unwrap() error { return e.error }
+
+type aborted struct{ error }
+
+func (aborted) Aborted() {}
+
+func (e aborted) Unwrap() error { return e.error }
+
+type outOfRange struct{ error }
+
+func (outOfRange) OutOfRange() {}
+
+func (e outOfRange) Unwrap() error { return e.error }
+
+type notImplemented struct{ error }
+
+func (notImplemented) NotImplemented() {}
+
+func (e notImplemented) Unwrap() error { return e.error }
+
+type internal struct{ error }
+
+func (internal) System() {}
+
+func (e internal) Unwrap() error { return e.error }
+
+type unavailable struct{ error }
+
+func (unavailable) Unavailable() {}
+
+func (e unavailable) Unwrap() error { return e.error }
+
+type dataLoss struct{ error }
+
+func (dataLoss) DataLoss() {}
+
+func (e dataLoss) Unwrap() error { return e.error }
+
+type unauthenticated struct{ error }
+
+func (unauthenticated) Unauthorized() {}
+
+func (e unauthenticated) Unwrap() error { return e.error }
+
+type deadlineExceeded struct{ error }
+
+func (deadlineExceeded) DeadlineExceeded() {}
+
+func (e deadlineExceeded) Unwrap() error { return e.error }
+
+type canceled struct{ error }
+
+func (canceled) Cancelled() {}
+
+func (e canceled) Unwrap() error { return e.error }
@@ -0,0 +1,112 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package redact
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// TestDefaultCorpus applies the default redactor to each line of
+// testdata/corpus.txt and compares the result with testdata/corpus.golden.
+// Run with -update to regenerate the golden file after changing the rules.
+func TestDefaultCorpus(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "corpus.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	r := Default()
+	s := bufio.NewScanner(bytes.NewReader(input))
+	for s.Scan() {
+		out.WriteString(r.String(s.Text()))
+		out.WriteByte('\n')
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "corpus.golden")
+	if *update {
+		if err := os.WriteFile(golden, out.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(out.String(), "\n")
+	for i, line := range strings.Split(string(expected), "\n") {
+		if i >= len(got) || got[i] != line {
+			t.Errorf("line %d: expected %q, got %q", i+1, line, got[min(i, len(got)-1)])
+		}
+	}
+	if bytes.Contains(out.Bytes(), []byte("s3cr3t")) || bytes.Contains(out.Bytes(), []byte("alice")) {
+		t.Fatal("sensitive values not redacted")
+	}
+}
+
+type detail struct {
+	args []string
+}
+
+func (d detail) Error() string {
+	return "detail"
+}
+
+func (d detail) Redact(fn func(string) string) error {
+	args := make([]string, len(d.args))
+	for i, a := range d.args {
+		args[i] = fn(a)
+	}
+	return detail{args: args}
+}
+
+func TestRedactor(t *testing.T) {
+	r := New(Regexp(regexp.MustCompile(`id-[0-9]+`), "id-X"), func(s string) string {
+		return strings.ToUpper(s)
+	})
+	if s := r.String("lookup id-1234 failed"); s != "LOOKUP ID-X FAILED" {
+		t.Fatalf("unexpected redacted text %q", s)
+	}
+
+	var nr *Redactor
+	if s := nr.String("token=abc"); s != "token=abc" {
+		t.Fatalf("nil redactor should not change text, got %q", s)
+	}
+
+	d := detail{args: []string{"ctr", "--password=abc", "/home/alice"}}
+	rd, ok := Default().Detail(d).(detail)
+	if !ok {
+		t.Fatal("expected redacted detail")
+	}
+	if expected := []string{"ctr", "--password=" + Placeholder, "/home/" + Placeholder}; strings.Join(rd.args, " ") != strings.Join(expected, " ") {
+		t.Fatalf("unexpected redacted detail %v", rd.args)
+	}
+	if nd := nr.Detail(d); nd.(detail).args[1] != "--password=abc" {
+		t.Fatal("nil redactor should not change detail")
+	}
+}
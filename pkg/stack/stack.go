@@ -188,6 +188,28 @@ func (s *stack) StackTrace() Trace {
 	return *s.getDecoded()
 }
 
+// Redact returns a copy of the stack with the function applied to the
+// command line arguments and the file of each frame, which may contain
+// credentials and home directories.
+func (s *stack) Redact(fn func(string) string) error {
+	d := s.getDecoded()
+	t := *d
+	if t.Cmdline != nil {
+		t.Cmdline = make([]string, len(d.Cmdline))
+		for i, arg := range d.Cmdline {
+			t.Cmdline[i] = fn(arg)
+		}
+	}
+	if t.Frames != nil {
+		t.Frames = make([]Frame, len(d.Frames))
+		for i, f := range d.Frames {
+			f.File = fn(f.File)
+			t.Frames[i] = f
+		}
+	}
+	return &stack{decoded: &t}
+}
+
 func (s *stack) CollapseError() {}
 
 // ErrStack returns a new error for the callers stack,
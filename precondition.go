@@ -0,0 +1,84 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import "strings"
+
+// PreconditionViolation is a failed precondition error describing a single
+// precondition of an operation which was not met. Several violations may be
+// returned together using FailedPreconditions or errors.Join, and retrieved
+// using PreconditionViolations.
+type PreconditionViolation struct {
+	// Type is an upper snake case identifier for the kind of precondition
+	// which failed, such as "IN_USE" or "LEASE_MISSING".
+	Type string
+
+	// Subject identifies what failed the precondition, relative to the
+	// type, such as the key of a snapshot.
+	Subject string
+
+	// Description explains how the precondition failed.
+	Description string
+}
+
+func (v PreconditionViolation) Error() string {
+	desc := v.Description
+	if desc == "" {
+		desc = ErrFailedPrecondition.Error()
+	}
+	if v.Subject == "" {
+		return desc
+	}
+	return v.Subject + ": " + desc
+}
+
+// FailedPrecondition marks the violation as a failed precondition error
+func (PreconditionViolation) FailedPrecondition() {}
+
+// FailedPreconditions is a failed precondition error for an operation with
+// one or more preconditions which were not met.
+type FailedPreconditions []PreconditionViolation
+
+func (e FailedPreconditions) Error() string {
+	if len(e) == 0 {
+		return ErrFailedPrecondition.Error()
+	}
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = v.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// FailedPrecondition marks the violations as a failed precondition error
+func (FailedPreconditions) FailedPrecondition() {}
+
+// Err returns the violations as an error, or nil if there are none.
+func (e FailedPreconditions) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// PreconditionViolations returns all the precondition violations found in
+// the error, either as PreconditionViolation or FailedPreconditions errors,
+// in the order they are found in the error tree. Nil is returned if there
+// are no violations.
+func PreconditionViolations(err error) FailedPreconditions {
+	return collectViolations[PreconditionViolation, FailedPreconditions](err)
+}
@@ -0,0 +1,59 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFailedPreconditions(t *testing.T) {
+	inUse := PreconditionViolation{Type: "IN_USE", Subject: "snapshot/sha256:abc", Description: "in use by 3 containers"}
+	lease := PreconditionViolation{Type: "LEASE_MISSING", Subject: "lease/pull-1"}
+
+	err := fmt.Errorf("remove: %w", FailedPreconditions{inUse, lease}.Err())
+	if !IsFailedPrecondition(err) || ClassOf(err) != ClassFailedPrecondition {
+		t.Fatalf("expected failed precondition, got %v", ClassOf(err))
+	}
+	if IsConflict(err) || IsNotModified(err) {
+		t.Fatalf("unexpected class for %v", err)
+	}
+	if msg := err.Error(); msg != "remove: snapshot/sha256:abc: in use by 3 containers; lease/pull-1: failed precondition" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+	var target FailedPreconditions
+	if !errors.As(err, &target) || !reflect.DeepEqual(target, FailedPreconditions{inUse, lease}) {
+		t.Fatalf("errors.As should match FailedPreconditions: %v", err)
+	}
+
+	joined := errors.Join(fmt.Errorf("gc: %w", inUse), lease)
+	if !IsFailedPrecondition(joined) {
+		t.Fatalf("expected failed precondition: %v", joined)
+	}
+	var v PreconditionViolation
+	if !errors.As(joined, &v) || v.Type != "IN_USE" {
+		t.Fatalf("errors.As should match PreconditionViolation: %v", joined)
+	}
+	if pv := PreconditionViolations(joined); !reflect.DeepEqual(pv, FailedPreconditions{inUse, lease}) {
+		t.Fatalf("unexpected violations %v", pv)
+	}
+	if FailedPreconditions(nil).Err() != nil || PreconditionViolations(ErrFailedPrecondition) != nil {
+		t.Fatal("unexpected violations")
+	}
+}
@@ -0,0 +1,98 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// QuotaViolation is a resource exhausted error describing a single quota
+// which was exceeded. Several violations may be returned together using
+// ExceededQuotas or errors.Join, and retrieved using QuotaViolations.
+type QuotaViolation struct {
+	// Subject identifies what the quota applies to, such as
+	// "namespace:default".
+	Subject string
+
+	// Limit is the name of the quota limit which was exceeded, such as
+	// "snapshots".
+	Limit string
+
+	// Current is the current usage counted against the limit.
+	Current int64
+
+	// Max is the value of the limit.
+	Max int64
+
+	// Description explains how the quota was exceeded.
+	Description string
+}
+
+func (v QuotaViolation) Error() string {
+	desc := v.Description
+	if desc == "" {
+		if v.Limit != "" {
+			desc = v.Limit + " quota exceeded"
+			if v.Max > 0 {
+				desc += " (" + strconv.FormatInt(v.Current, 10) + "/" + strconv.FormatInt(v.Max, 10) + ")"
+			}
+		} else {
+			desc = ErrResourceExhausted.Error()
+		}
+	}
+	if v.Subject == "" {
+		return desc
+	}
+	return v.Subject + ": " + desc
+}
+
+// ResourceExhausted marks the violation as a resource exhausted error
+func (QuotaViolation) ResourceExhausted() {}
+
+// ExceededQuotas is a resource exhausted error for an operation which
+// exceeded one or more quotas.
+type ExceededQuotas []QuotaViolation
+
+func (e ExceededQuotas) Error() string {
+	if len(e) == 0 {
+		return ErrResourceExhausted.Error()
+	}
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = v.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ResourceExhausted marks the violations as a resource exhausted error
+func (ExceededQuotas) ResourceExhausted() {}
+
+// Err returns the violations as an error, or nil if there are none.
+func (e ExceededQuotas) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// QuotaViolations returns all the quota violations found in the error,
+// either as QuotaViolation or ExceededQuotas errors, in the order they are
+// found in the error tree. Nil is returned if there are no violations.
+func QuotaViolations(err error) ExceededQuotas {
+	return collectViolations[QuotaViolation, ExceededQuotas](err)
+}
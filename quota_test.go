@@ -0,0 +1,62 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestExceededQuotas(t *testing.T) {
+	for _, tc := range []struct {
+		v   QuotaViolation
+		msg string
+	}{
+		{QuotaViolation{}, "resource exhausted"},
+		{QuotaViolation{Subject: "namespace:default", Limit: "snapshots", Current: 100, Max: 100}, "namespace:default: snapshots quota exceeded (100/100)"},
+		{QuotaViolation{Limit: "pulls"}, "pulls quota exceeded"},
+		{QuotaViolation{Subject: "node:a", Limit: "memory", Description: "not enough memory"}, "node:a: not enough memory"},
+	} {
+		if msg := tc.v.Error(); msg != tc.msg {
+			t.Errorf("unexpected message %q, expected %q", msg, tc.msg)
+		}
+	}
+
+	snapshots := QuotaViolation{Subject: "namespace:default", Limit: "snapshots", Current: 100, Max: 100}
+	leases := QuotaViolation{Subject: "namespace:default", Limit: "leases", Current: 12, Max: 10}
+	err := fmt.Errorf("prepare: %w", ExceededQuotas{snapshots, leases}.Err())
+	if !IsResourceExhausted(err) || ClassOf(err) != ClassResourceExhausted {
+		t.Fatalf("expected resource exhausted, got %v", ClassOf(err))
+	}
+	var target ExceededQuotas
+	if !errors.As(err, &target) || !reflect.DeepEqual(target, ExceededQuotas{snapshots, leases}) {
+		t.Fatalf("errors.As should match ExceededQuotas: %v", err)
+	}
+
+	joined := errors.Join(snapshots, fmt.Errorf("lease: %w", leases))
+	if !IsResourceExhausted(joined) {
+		t.Fatalf("expected resource exhausted: %v", joined)
+	}
+	if qv := QuotaViolations(joined); !reflect.DeepEqual(qv, ExceededQuotas{snapshots, leases}) {
+		t.Fatalf("unexpected violations %v", qv)
+	}
+	if ExceededQuotas(nil).Err() != nil || QuotaViolations(ErrResourceExhausted) != nil {
+		t.Fatal("unexpected violations")
+	}
+}
@@ -0,0 +1,70 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import "errors"
+
+// ReasonInfo is a machine readable reason for an error, allowing clients to
+// handle specific errors within a class without parsing the message. It
+// follows the google.rpc.ErrorInfo message used by gRPC services.
+type ReasonInfo struct {
+	// Domain is the logical grouping the reason belongs to, typically the
+	// name of the service or component which defines the reason, such as
+	// "leases.containerd.io".
+	Domain string
+
+	// Reason is the upper snake case identifier of the reason, unique
+	// within the domain, such as "LEASE_EXPIRED".
+	Reason string
+
+	// Metadata is additional structured information about the error,
+	// such as the identifier of the expired lease.
+	Metadata map[string]string
+}
+
+// WithReason returns the error annotated with the reason. The returned error
+// keeps the message and class of err and unwraps to it. A nil error is
+// returned unchanged.
+//
+// The reason is carried across gRPC and HTTP by the errgrpc and errhttp
+// packages.
+func WithReason(err error, domain, reason string, metadata map[string]string) error {
+	if err == nil {
+		return nil
+	}
+	return reasonError{error: err, info: ReasonInfo{Domain: domain, Reason: reason, Metadata: metadata}}
+}
+
+// ReasonOf returns the reason of the error, as set by WithReason. When
+// reasons were added multiple times, the outermost reason is returned. False
+// is returned if the error has no reason.
+func ReasonOf(err error) (ReasonInfo, bool) {
+	var re reasonError
+	if errors.As(err, &re) {
+		return re.info, true
+	}
+	return ReasonInfo{}, false
+}
+
+type reasonError struct {
+	error
+	info ReasonInfo
+}
+
+func (e reasonError) Unwrap() error {
+	return e.error
+}
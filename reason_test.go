@@ -0,0 +1,48 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestReason(t *testing.T) {
+	if WithReason(nil, "containerd.io", "LEASE_EXPIRED", nil) != nil {
+		t.Fatal("expected nil error")
+	}
+	if _, ok := ReasonOf(ErrNotFound); ok {
+		t.Fatal("unexpected reason on sentinel")
+	}
+
+	md := map[string]string{"lease": "pull-1"}
+	err := fmt.Errorf("commit: %w", WithReason(ErrFailedPrecondition.WithMessage("lease expired"), "containerd.io", "LEASE_EXPIRED", md))
+	if !IsFailedPrecondition(err) || err.Error() != "commit: lease expired" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	info, ok := ReasonOf(err)
+	if !ok || !reflect.DeepEqual(info, ReasonInfo{Domain: "containerd.io", Reason: "LEASE_EXPIRED", Metadata: md}) {
+		t.Fatalf("unexpected reason %+v", info)
+	}
+
+	outer := WithReason(errors.Join(err, ErrAborted), "example.com", "GC_RUNNING", nil)
+	if info, _ := ReasonOf(outer); info.Reason != "GC_RUNNING" {
+		t.Fatalf("expected outermost reason, got %+v", info)
+	}
+}
@@ -88,7 +88,7 @@ func firstError(err error) error {
 			return ErrNotModified
 		case aborted:
 			return ErrAborted
-		case errOutOfRange:
+		case outOfRange:
 			return ErrOutOfRange
 		case notImplemented:
 			return ErrNotImplemented
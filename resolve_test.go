@@ -58,6 +58,8 @@ func TestResolve(t *testing.T) {
 		{context.Canceled, context.Canceled},
 		{testUnavailable{}, ErrUnavailable},
 		{wrap(testUnavailable{}), ErrUnavailable},
+		{testOutOfRange{}, ErrOutOfRange},
+		{wrap(testOutOfRange{}), ErrOutOfRange},
 		{errors.Join(testUnavailable{}, ErrPermissionDenied), ErrUnavailable},
 		{errors.Join(errors.New("untyped join")), ErrUnknown},
 		{errors.Join(errors.New("untyped1"), errors.New("untyped2")), ErrUnknown},
@@ -92,3 +94,8 @@ type testUnavailable struct{}
 
 func (testUnavailable) Error() string { return "" }
 func (testUnavailable) Unavailable()  {}
+
+type testOutOfRange struct{}
+
+func (testOutOfRange) Error() string { return "" }
+func (testOutOfRange) OutOfRange()   {}
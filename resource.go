@@ -0,0 +1,101 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import "strconv"
+
+// ResourceError is an error for a specific resource, such as an image which
+// was not found or a container which already exists. The class of the error
+// is the class of Err, which the error unwraps to.
+//
+// Use errors.As to retrieve the resource from an error.
+type ResourceError struct {
+	// Type is the kind of resource, such as "image", "snapshot", "content"
+	// or "container".
+	Type string
+
+	// Name identifies the resource, such as an image reference or a
+	// content digest.
+	Name string
+
+	// Owner is the optional owner of the resource, such as its namespace.
+	Owner string
+
+	// Operation is the optional operation which failed on the resource,
+	// such as "create" or "delete".
+	Operation string
+
+	// Err is the error for the class of the error, such as ErrNotFound.
+	Err error
+}
+
+// ResourceNotFound returns a not found error for the resource
+func ResourceNotFound(typ, name string) *ResourceError {
+	return &ResourceError{Type: typ, Name: name, Err: ErrNotFound}
+}
+
+// ResourceAlreadyExists returns an already exists error for the resource
+func ResourceAlreadyExists(typ, name string) *ResourceError {
+	return &ResourceError{Type: typ, Name: name, Err: ErrAlreadyExists}
+}
+
+// ResourcePermissionDenied returns a permission denied error for the
+// resource
+func ResourcePermissionDenied(typ, name string) *ResourceError {
+	return &ResourceError{Type: typ, Name: name, Err: ErrPermissionDenied}
+}
+
+func (e *ResourceError) Error() string {
+	cls := e.Err
+	if cls == nil {
+		cls = ErrUnknown
+	}
+	if e.Name == "" {
+		if e.Type == "" {
+			return cls.Error()
+		}
+		return e.Type + ": " + cls.Error()
+	}
+	if e.Type == "" {
+		return strconv.Quote(e.Name) + ": " + cls.Error()
+	}
+	return e.Type + " " + strconv.Quote(e.Name) + ": " + cls.Error()
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
+// ResourceErrors returns all the resource errors found in the error, in the
+// order they are found in the error tree. Nil is returned if there are none.
+func ResourceErrors(err error) []*ResourceError {
+	switch e := err.(type) {
+	case *ResourceError:
+		if e != nil {
+			return []*ResourceError{e}
+		}
+	case interface{ Unwrap() error }:
+		return ResourceErrors(e.Unwrap())
+	case interface{ Unwrap() []error }:
+		var errs []*ResourceError
+		for _, ue := range e.Unwrap() {
+			errs = append(errs, ResourceErrors(ue)...)
+		}
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestResourceError(t *testing.T) {
+	for _, tc := range []struct {
+		err   *ResourceError
+		class Class
+		msg   string
+	}{
+		{ResourceNotFound("image", "docker.io/library/redis:latest"), ClassNotFound, `image "docker.io/library/redis:latest": not found`},
+		{ResourceAlreadyExists("container", "redis"), ClassAlreadyExists, `container "redis": already exists`},
+		{ResourcePermissionDenied("content", ""), ClassPermissionDenied, "content: permission denied"},
+		{&ResourceError{Name: "sha256:abc", Err: ErrFailedPrecondition}, ClassFailedPrecondition, `"sha256:abc": failed precondition`},
+		{&ResourceError{Type: "snapshot"}, ClassUnknown, "snapshot: unknown"},
+	} {
+		if c := ClassOf(tc.err); c != tc.class {
+			t.Errorf("%v: unexpected class %v", tc.err, c)
+		}
+		if msg := tc.err.Error(); msg != tc.msg {
+			t.Errorf("unexpected message %q, expected %q", msg, tc.msg)
+		}
+	}
+
+	rerr := ResourceNotFound("snapshot", "k8s.io/5/rootfs")
+	rerr.Owner = "k8s.io"
+	rerr.Operation = "stat"
+	err := fmt.Errorf("prepare: %w", rerr)
+	if !IsNotFound(err) || !errors.Is(err, ErrNotFound) || IsAlreadyExists(err) {
+		t.Fatalf("unexpected class for %v", err)
+	}
+	var target *ResourceError
+	if !errors.As(err, &target) || target.Owner != "k8s.io" || target.Operation != "stat" {
+		t.Fatalf("errors.As should match the resource error: %v", err)
+	}
+
+	joined := errors.Join(err, ResourceAlreadyExists("lease", "pull-1"))
+	if res := ResourceErrors(joined); len(res) != 2 || res[0] != rerr || res[1].Type != "lease" {
+		t.Fatalf("unexpected resource errors %v", res)
+	}
+	if ResourceErrors(ErrNotFound) != nil {
+		t.Fatal("unexpected resource errors")
+	}
+}
@@ -0,0 +1,93 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"errors"
+	"time"
+)
+
+// IsRetryable returns true if an operation which failed with the error may
+// succeed when attempted again without any change to its input. The
+// decision is made on the class of the error, see ClassOf:
+//
+//   - ClassUnavailable, ClassResourceExhausted and ClassAborted are always
+//     retryable, the operation was not performed or was rolled back and
+//     may succeed once the condition clears.
+//   - ClassDeadlineExceeded and ClassUnknown are retryable only when the
+//     operation is idempotent, since the operation may have completed
+//     before the error was returned.
+//   - ClassCanceled is never retryable, the caller requested the
+//     operation to stop. This includes errors matching IsCanceled which
+//     resolve to another class, such as a canceled error joined with an
+//     unavailable error.
+//   - All other classes are terminal, attempting the operation again will
+//     return the same error.
+//
+// A nil error is not retryable.
+func IsRetryable(err error, idempotent bool) bool {
+	if err == nil || IsCanceled(err) {
+		return false
+	}
+	switch ClassOf(err) {
+	case ClassUnavailable, ClassResourceExhausted, ClassAborted:
+		return true
+	case ClassDeadlineExceeded, ClassUnknown:
+		return idempotent
+	default:
+		return false
+	}
+}
+
+// WithRetryAfter returns the error annotated with the delay after which the
+// failed operation may be attempted again, such as the delay requested by a
+// rate limited server. The returned error keeps the message and class of err
+// and unwraps to it. A nil error is returned unchanged.
+//
+// The delay is carried across gRPC and HTTP by the errgrpc and errhttp
+// packages, and is honored by errretry.
+func WithRetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return retryAfter{error: err, delay: d}
+}
+
+// RetryAfter returns the delay after which the operation which failed with
+// the error may be attempted again. False is returned if no error in the
+// chain has a RetryAfter() time.Duration method, such as those returned by
+// WithRetryAfter.
+func RetryAfter(err error) (time.Duration, bool) {
+	var ra interface{ RetryAfter() time.Duration }
+	if errors.As(err, &ra) {
+		return ra.RetryAfter(), true
+	}
+	return 0, false
+}
+
+type retryAfter struct {
+	error
+	delay time.Duration
+}
+
+func (e retryAfter) Unwrap() error {
+	return e.error
+}
+
+func (e retryAfter) RetryAfter() time.Duration {
+	return e.delay
+}
@@ -0,0 +1,80 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package errdefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		err           error
+		retryable     bool
+		idempotentRet bool
+	}{
+		{nil, false, false},
+		{errors.New("untyped"), false, true},
+		{ErrUnavailable, true, true},
+		{fmt.Errorf("pull: %w", ErrResourceExhausted), true, true},
+		{ErrAborted.WithMessage("transaction conflict"), true, true},
+		{context.DeadlineExceeded, false, true},
+		{ErrUnknown, false, true},
+		{context.Canceled, false, false},
+		{fmt.Errorf("stopped: %w", context.Canceled), false, false},
+		{errors.Join(fmt.Errorf("dial: %w", ErrUnavailable), context.Canceled), false, false},
+		{ErrNotFound, false, false},
+		{ErrInvalidArgument, false, false},
+		{ErrInternal, false, false},
+		{ErrDataLoss, false, false},
+		{ErrFailedPrecondition, false, false},
+		{ErrConflict, false, false},
+	} {
+		if r := IsRetryable(tc.err, false); r != tc.retryable {
+			t.Errorf("IsRetryable(%v, false) = %t", tc.err, r)
+		}
+		if r := IsRetryable(tc.err, true); r != tc.idempotentRet {
+			t.Errorf("IsRetryable(%v, true) = %t", tc.err, r)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if WithRetryAfter(nil, time.Second) != nil {
+		t.Fatal("expected nil error")
+	}
+	if _, ok := RetryAfter(ErrUnavailable); ok {
+		t.Fatal("unexpected retry after on sentinel")
+	}
+
+	err := fmt.Errorf("pull: %w", WithRetryAfter(ErrResourceExhausted.WithMessage("rate limited"), 30*time.Second))
+	if !IsResourceExhausted(err) || ClassOf(err) != ClassResourceExhausted {
+		t.Fatalf("class not preserved: %v", err)
+	}
+	if err.Error() != "pull: rate limited" {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+	if d, ok := RetryAfter(err); !ok || d != 30*time.Second {
+		t.Fatalf("unexpected retry after %v, %t", d, ok)
+	}
+	if d, ok := RetryAfter(errors.Join(ErrNotFound, WithRetryAfter(ErrUnavailable, time.Minute))); !ok || d != time.Minute {
+		t.Fatalf("retry after not found in joined error: %v, %t", d, ok)
+	}
+}